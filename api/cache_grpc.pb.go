@@ -22,7 +22,16 @@ const _ = grpc.SupportPackageIsVersion7
 const (
 	CacheService_Get_FullMethodName              = "/api.CacheService/Get"
 	CacheService_Put_FullMethodName              = "/api.CacheService/Put"
+	CacheService_Delete_FullMethodName           = "/api.CacheService/Delete"
+	CacheService_CompareAndSwap_FullMethodName   = "/api.CacheService/CompareAndSwap"
+	CacheService_Incr_FullMethodName             = "/api.CacheService/Incr"
+	CacheService_MGet_FullMethodName             = "/api.CacheService/MGet"
+	CacheService_MPut_FullMethodName             = "/api.CacheService/MPut"
 	CacheService_Len_FullMethodName              = "/api.CacheService/Len"
+	CacheService_Stats_FullMethodName            = "/api.CacheService/Stats"
+	CacheService_Scan_FullMethodName             = "/api.CacheService/Scan"
+	CacheService_Flush_FullMethodName            = "/api.CacheService/Flush"
+	CacheService_Watch_FullMethodName            = "/api.CacheService/Watch"
 	CacheService_GetClusterConfig_FullMethodName = "/api.CacheService/GetClusterConfig"
 )
 
@@ -32,7 +41,34 @@ const (
 type CacheServiceClient interface {
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
 	Put(ctx context.Context, in *PutRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// CompareAndSwap writes the value only if the current version of the
+	// key is equal to the expected one, otherwise it fails with Aborted.
+	CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error)
+	// Incr atomically adds delta to the value, stored as a decimal int64,
+	// it fails with FailedPrecondition, when the value isn't an integer,
+	// and with OutOfRange, when the result overflows.
+	Incr(ctx context.Context, in *IncrRequest, opts ...grpc.CallOption) (*IncrResponse, error)
+	// MGet and MPut are batched versions of Get and Put, they are used
+	// to reduce the number of round trips, when working with many keys
+	// owned by the same node.
+	MGet(ctx context.Context, in *MGetRequest, opts ...grpc.CallOption) (*MGetResponse, error)
+	MPut(ctx context.Context, in *MPutRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Len(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*LengthResponse, error)
+	Stats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Scan returns the page of keys with the prefix, stored on the node.
+	Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error)
+	// Flush removes keys from the node, it's an admin call, so the admin
+	// token must be passed as "authorization: Bearer <token>" metadata.
+	//
+	// every flushed key is published to watchers as DELETE.
+	Flush(ctx context.Context, in *FlushRequest, opts ...grpc.CallOption) (*FlushResponse, error)
+	// Watch streams changes of the key or keys with the prefix, stored on
+	// the node, until the client cancels the call.
+	//
+	// events are not buffered infinitely, slow watchers are disconnected
+	// with ResourceExhausted and are expected to subscribe again.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CacheService_WatchClient, error)
 	// GetClusterConfig is used to get the cluster configuration
 	// from client side, to have up-to-date cluster configuration.
 	GetClusterConfig(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ClusterConfig, error)
@@ -64,6 +100,51 @@ func (c *cacheServiceClient) Put(ctx context.Context, in *PutRequest, opts ...gr
 	return out, nil
 }
 
+func (c *cacheServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, CacheService_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) CompareAndSwap(ctx context.Context, in *CompareAndSwapRequest, opts ...grpc.CallOption) (*CompareAndSwapResponse, error) {
+	out := new(CompareAndSwapResponse)
+	err := c.cc.Invoke(ctx, CacheService_CompareAndSwap_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Incr(ctx context.Context, in *IncrRequest, opts ...grpc.CallOption) (*IncrResponse, error) {
+	out := new(IncrResponse)
+	err := c.cc.Invoke(ctx, CacheService_Incr_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) MGet(ctx context.Context, in *MGetRequest, opts ...grpc.CallOption) (*MGetResponse, error) {
+	out := new(MGetResponse)
+	err := c.cc.Invoke(ctx, CacheService_MGet_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) MPut(ctx context.Context, in *MPutRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, CacheService_MPut_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cacheServiceClient) Len(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*LengthResponse, error) {
 	out := new(LengthResponse)
 	err := c.cc.Invoke(ctx, CacheService_Len_FullMethodName, in, out, opts...)
@@ -73,6 +154,65 @@ func (c *cacheServiceClient) Len(ctx context.Context, in *emptypb.Empty, opts ..
 	return out, nil
 }
 
+func (c *cacheServiceClient) Stats(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, CacheService_Stats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Scan(ctx context.Context, in *ScanRequest, opts ...grpc.CallOption) (*ScanResponse, error) {
+	out := new(ScanResponse)
+	err := c.cc.Invoke(ctx, CacheService_Scan_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Flush(ctx context.Context, in *FlushRequest, opts ...grpc.CallOption) (*FlushResponse, error) {
+	out := new(FlushResponse)
+	err := c.cc.Invoke(ctx, CacheService_Flush_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cacheServiceClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CacheService_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CacheService_ServiceDesc.Streams[0], CacheService_Watch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cacheServiceWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CacheService_WatchClient interface {
+	Recv() (*WatchEvent, error)
+	grpc.ClientStream
+}
+
+type cacheServiceWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *cacheServiceWatchClient) Recv() (*WatchEvent, error) {
+	m := new(WatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *cacheServiceClient) GetClusterConfig(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*ClusterConfig, error) {
 	out := new(ClusterConfig)
 	err := c.cc.Invoke(ctx, CacheService_GetClusterConfig_FullMethodName, in, out, opts...)
@@ -88,7 +228,34 @@ func (c *cacheServiceClient) GetClusterConfig(ctx context.Context, in *emptypb.E
 type CacheServiceServer interface {
 	Get(context.Context, *GetRequest) (*GetResponse, error)
 	Put(context.Context, *PutRequest) (*emptypb.Empty, error)
+	Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error)
+	// CompareAndSwap writes the value only if the current version of the
+	// key is equal to the expected one, otherwise it fails with Aborted.
+	CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error)
+	// Incr atomically adds delta to the value, stored as a decimal int64,
+	// it fails with FailedPrecondition, when the value isn't an integer,
+	// and with OutOfRange, when the result overflows.
+	Incr(context.Context, *IncrRequest) (*IncrResponse, error)
+	// MGet and MPut are batched versions of Get and Put, they are used
+	// to reduce the number of round trips, when working with many keys
+	// owned by the same node.
+	MGet(context.Context, *MGetRequest) (*MGetResponse, error)
+	MPut(context.Context, *MPutRequest) (*emptypb.Empty, error)
 	Len(context.Context, *emptypb.Empty) (*LengthResponse, error)
+	Stats(context.Context, *emptypb.Empty) (*StatsResponse, error)
+	// Scan returns the page of keys with the prefix, stored on the node.
+	Scan(context.Context, *ScanRequest) (*ScanResponse, error)
+	// Flush removes keys from the node, it's an admin call, so the admin
+	// token must be passed as "authorization: Bearer <token>" metadata.
+	//
+	// every flushed key is published to watchers as DELETE.
+	Flush(context.Context, *FlushRequest) (*FlushResponse, error)
+	// Watch streams changes of the key or keys with the prefix, stored on
+	// the node, until the client cancels the call.
+	//
+	// events are not buffered infinitely, slow watchers are disconnected
+	// with ResourceExhausted and are expected to subscribe again.
+	Watch(*WatchRequest, CacheService_WatchServer) error
 	// GetClusterConfig is used to get the cluster configuration
 	// from client side, to have up-to-date cluster configuration.
 	GetClusterConfig(context.Context, *emptypb.Empty) (*ClusterConfig, error)
@@ -105,9 +272,36 @@ func (UnimplementedCacheServiceServer) Get(context.Context, *GetRequest) (*GetRe
 func (UnimplementedCacheServiceServer) Put(context.Context, *PutRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Put not implemented")
 }
+func (UnimplementedCacheServiceServer) Delete(context.Context, *DeleteRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedCacheServiceServer) CompareAndSwap(context.Context, *CompareAndSwapRequest) (*CompareAndSwapResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompareAndSwap not implemented")
+}
+func (UnimplementedCacheServiceServer) Incr(context.Context, *IncrRequest) (*IncrResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Incr not implemented")
+}
+func (UnimplementedCacheServiceServer) MGet(context.Context, *MGetRequest) (*MGetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MGet not implemented")
+}
+func (UnimplementedCacheServiceServer) MPut(context.Context, *MPutRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MPut not implemented")
+}
 func (UnimplementedCacheServiceServer) Len(context.Context, *emptypb.Empty) (*LengthResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Len not implemented")
 }
+func (UnimplementedCacheServiceServer) Stats(context.Context, *emptypb.Empty) (*StatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedCacheServiceServer) Scan(context.Context, *ScanRequest) (*ScanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedCacheServiceServer) Flush(context.Context, *FlushRequest) (*FlushResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Flush not implemented")
+}
+func (UnimplementedCacheServiceServer) Watch(*WatchRequest, CacheService_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
 func (UnimplementedCacheServiceServer) GetClusterConfig(context.Context, *emptypb.Empty) (*ClusterConfig, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetClusterConfig not implemented")
 }
@@ -160,6 +354,96 @@ func _CacheService_Put_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CacheService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_CompareAndSwap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareAndSwapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).CompareAndSwap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_CompareAndSwap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).CompareAndSwap(ctx, req.(*CompareAndSwapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Incr_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Incr(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Incr_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Incr(ctx, req.(*IncrRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_MGet_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).MGet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_MGet_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).MGet(ctx, req.(*MGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_MPut_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MPutRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).MPut(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_MPut_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).MPut(ctx, req.(*MPutRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CacheService_Len_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -178,6 +462,81 @@ func _CacheService_Len_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CacheService_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Stats(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Scan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Scan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Scan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Scan(ctx, req.(*ScanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Flush_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FlushRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CacheServiceServer).Flush(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CacheService_Flush_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CacheServiceServer).Flush(ctx, req.(*FlushRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CacheService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CacheServiceServer).Watch(m, &cacheServiceWatchServer{stream})
+}
+
+type CacheService_WatchServer interface {
+	Send(*WatchEvent) error
+	grpc.ServerStream
+}
+
+type cacheServiceWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *cacheServiceWatchServer) Send(m *WatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _CacheService_GetClusterConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(emptypb.Empty)
 	if err := dec(in); err != nil {
@@ -211,15 +570,53 @@ var CacheService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Put",
 			Handler:    _CacheService_Put_Handler,
 		},
+		{
+			MethodName: "Delete",
+			Handler:    _CacheService_Delete_Handler,
+		},
+		{
+			MethodName: "CompareAndSwap",
+			Handler:    _CacheService_CompareAndSwap_Handler,
+		},
+		{
+			MethodName: "Incr",
+			Handler:    _CacheService_Incr_Handler,
+		},
+		{
+			MethodName: "MGet",
+			Handler:    _CacheService_MGet_Handler,
+		},
+		{
+			MethodName: "MPut",
+			Handler:    _CacheService_MPut_Handler,
+		},
 		{
 			MethodName: "Len",
 			Handler:    _CacheService_Len_Handler,
 		},
+		{
+			MethodName: "Stats",
+			Handler:    _CacheService_Stats_Handler,
+		},
+		{
+			MethodName: "Scan",
+			Handler:    _CacheService_Scan_Handler,
+		},
+		{
+			MethodName: "Flush",
+			Handler:    _CacheService_Flush_Handler,
+		},
 		{
 			MethodName: "GetClusterConfig",
 			Handler:    _CacheService_GetClusterConfig_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _CacheService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "cache.proto",
 }
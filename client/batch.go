@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/fadyat/speedy/api"
+	"github.com/fadyat/speedy/node"
+	"sync"
+	"time"
+)
+
+// groupByNode splits keys by their target nodes, keys without the node
+// are returned separately.
+func (c *client) groupByNode(keys []string) (map[*node.Node][]string, []string) {
+	var (
+		groups   = make(map[*node.Node][]string)
+		unrouted = make([]string, 0)
+	)
+
+	for _, key := range keys {
+		n := c.target(key)
+		if n == nil {
+			unrouted = append(unrouted, key)
+			continue
+		}
+
+		groups[n] = append(groups[n], key)
+	}
+
+	return groups, unrouted
+}
+
+// fanOut calls fn for each node in parallel and collects the errors
+// for every key of the failed nodes.
+func fanOut(
+	groups map[*node.Node][]string,
+	fn func(n *node.Node, keys []string) error,
+) map[string]error {
+	var (
+		wg   sync.WaitGroup
+		mx   sync.Mutex
+		errs = make(map[string]error)
+	)
+
+	for n, keys := range groups {
+		wg.Add(1)
+
+		go func(n *node.Node, keys []string) {
+			defer wg.Done()
+
+			if err := fn(n, keys); err != nil {
+				mx.Lock()
+				defer mx.Unlock()
+
+				for _, key := range keys {
+					errs[key] = err
+				}
+			}
+		}(n, keys)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func (c *client) MGet(keys []string) (map[string][]byte, error) {
+	var (
+		mx               sync.Mutex
+		values           = make(map[string][]byte, len(keys))
+		groups, unrouted = c.groupByNode(keys)
+	)
+
+	errs := fanOut(groups, func(n *node.Node, keys []string) error {
+		release := c.track(n)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		resp, err := n.Request().MGet(ctx, &api.MGetRequest{Keys: keys})
+		if err != nil {
+			return asClientError(err)
+		}
+
+		mx.Lock()
+		defer mx.Unlock()
+
+		for k, v := range resp.Values {
+			values[k] = v
+		}
+
+		return nil
+	})
+
+	for _, key := range unrouted {
+		errs[key] = ErrCacheMiss
+	}
+
+	return values, asBatchError(errs)
+}
+
+func (c *client) MPut(items map[string][]byte, opts ...PutOption) error {
+	var keys = make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	groups, unrouted := c.groupByNode(keys)
+	errs := fanOut(groups, func(n *node.Node, keys []string) error {
+		release := c.track(n)
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		req := &api.MPutRequest{Items: make([]*api.PutRequest, 0, len(keys))}
+		for _, key := range keys {
+			req.Items = append(req.Items, newPutRequest(key, items[key], opts))
+		}
+
+		if _, err := n.Request().MPut(ctx, req); err != nil {
+			return fmt.Errorf("failed to put values in cache: %w", err)
+		}
+
+		for _, key := range keys {
+			c.invalidate(key, n)
+		}
+
+		return nil
+	})
+
+	for _, key := range unrouted {
+		errs[key] = ErrCacheMiss
+	}
+
+	return asBatchError(errs)
+}
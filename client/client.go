@@ -7,7 +7,10 @@ import (
 	"github.com/fadyat/speedy/node"
 	"github.com/fadyat/speedy/sharding"
 	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"hash/crc32"
+	"math"
+	"sync"
 	"time"
 )
 
@@ -17,6 +20,14 @@ type client struct {
 
 	syncPeriod time.Duration
 	errChSize  int
+	adminToken string
+
+	boundedLoadOpts []sharding.BoundedLoadOption
+
+	// topology is closed and replaced on every change of the cluster config,
+	// watchers are waiting on it to move their streams to the new owners.
+	topologyMx sync.Mutex
+	topology   chan struct{}
 }
 
 type Option func(*client)
@@ -27,6 +38,51 @@ func WithSyncPeriod(period time.Duration) Option {
 	}
 }
 
+// WithLoadEpsilon sets the allowed excess of the average load of nodes,
+// it's used only by sharding.BoundedLoadAlgorithm, see sharding.WithEpsilon.
+func WithLoadEpsilon(epsilon float64) Option {
+	return func(c *client) {
+		c.boundedLoadOpts = append(c.boundedLoadOpts, sharding.WithEpsilon(epsilon))
+	}
+}
+
+// WithAdminToken sets the token, which is passed to the admin calls,
+// like Flush.
+func WithAdminToken(token string) Option {
+	return func(c *client) {
+		c.adminToken = token
+	}
+}
+
+type putOptions struct {
+	ttl time.Duration
+}
+
+// PutOption is used to configure a single write to the cache.
+type PutOption func(*putOptions)
+
+// WithTTL sets the time after which the key expires on the node,
+// by default keys live until they're evicted.
+func WithTTL(ttl time.Duration) PutOption {
+	return func(o *putOptions) {
+		o.ttl = ttl
+	}
+}
+
+func newPutRequest(key string, value []byte, opts []PutOption) *api.PutRequest {
+	var o putOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	req := &api.PutRequest{Key: key, Value: value}
+	if o.ttl > 0 {
+		req.Ttl = durationpb.New(o.ttl)
+	}
+
+	return req
+}
+
 func NewClient(
 	configPath string,
 	algoType sharding.AlgorithmType,
@@ -37,75 +93,264 @@ func NewClient(
 		return nil, fmt.Errorf("failed to initialize nodes config: %w", err)
 	}
 
-	algo, err := sharding.NewAlgo(
-		algoType,
-		nodesConfig.GetShards(),
-		func(k string) uint32 { return crc32.ChecksumIEEE([]byte(k)) },
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize sharding algorithm: %w", err)
-	}
-
 	c := &client{
 		nodesConfig: nodesConfig,
-		algo:        algo,
 		syncPeriod:  2 * time.Second,
 		errChSize:   10,
+		topology:    make(chan struct{}),
 	}
 
 	for _, o := range opts {
 		o(c)
 	}
 
+	hashFn := func(k string) uint32 { return crc32.ChecksumIEEE([]byte(k)) }
+	if algoType == sharding.BoundedLoadAlgorithm {
+		c.algo = sharding.NewBoundedLoad(nodesConfig.GetShards(), hashFn, c.boundedLoadOpts...)
+		return c, nil
+	}
+
+	if c.algo, err = sharding.NewAlgo(algoType, nodesConfig.GetShards(), hashFn); err != nil {
+		return nil, fmt.Errorf("failed to initialize sharding algorithm: %w", err)
+	}
+
 	return c, nil
 }
 
-func (c *client) Get(key string) (string, error) {
-	shard := c.algo.GetShard(key)
+// owner returns the node, which owns the key, or nil, when there is no
+// such node in the current cluster config, watchers of the key follow it.
+func (c *client) owner(key string) *node.Node {
+	if pr, ok := c.algo.(sharding.PrimaryRouter); ok {
+		return c.node(pr.GetPrimaryShard(key))
+	}
+
+	return c.node(c.algo.GetShard(key))
+}
+
+// target returns the node for reads and writes of the key, algorithms,
+// which balance the load of nodes, may divert the key from its owner, so
+// the next node is filled by the writes, as the owner would be.
+func (c *client) target(key string) *node.Node {
+	return c.node(c.algo.GetShard(key))
+}
+
+// invalidate deletes the key from its owner after the write, which was
+// diverted to n, so the owner doesn't return the old value, when its load
+// is back to normal, the write itself is done, so the error is only logged.
+func (c *client) invalidate(key string, n *node.Node) {
+	owner := c.owner(key)
+	if owner == nil || owner.ID == n.ID {
+		return
+	}
+
+	if err := c.delete(owner, key); err != nil && err != ErrCacheMiss {
+		zap.L().Warn("failed to invalidate the key on its owner",
+			zap.String("node", owner.ID), zap.String("key", key), zap.Error(err),
+		)
+	}
+}
+
+// node returns the node of the shard from the current cluster config.
+func (c *client) node(shard *sharding.Shard) *node.Node {
 	if shard == nil {
-		return "", ErrCacheMiss
+		return nil
 	}
 
 	n := c.nodesConfig.GetNode(shard.ID)
 	if n == nil {
 		zap.L().Info("sharding and nodes config are not synced, got outdated shard")
-		return "", ErrCacheMiss
+		return nil
 	}
 
+	return n
+}
+
+// track reports the request to the node to the sharding algorithm, if it
+// balances the load of nodes, the returned func reports its end, batched
+// requests are counted once, as the single call to the node.
+func (c *client) track(n *node.Node) func() {
+	lt, ok := c.algo.(sharding.LoadTracker)
+	if !ok {
+		return func() {}
+	}
+
+	shard := n.ToShard()
+	lt.Acquire(shard)
+	return func() { lt.Release(shard) }
+}
+
+// nodes returns all nodes of the current cluster config.
+func (c *client) nodes() []*node.Node {
+	var nodes = make([]*node.Node, 0)
+	for _, shard := range c.nodesConfig.GetShards() {
+		if n := c.nodesConfig.GetNode(shard.ID); n != nil {
+			nodes = append(nodes, n)
+		}
+	}
+
+	return nodes
+}
+
+func (c *client) Get(key string) ([]byte, error) {
+	val, _, err := c.GetWithVersion(key)
+	return val, err
+}
+
+func (c *client) GetWithVersion(key string) ([]byte, uint64, error) {
+	n := c.target(key)
+	if n == nil {
+		return nil, 0, ErrCacheMiss
+	}
+
+	release := c.track(n)
+	defer release()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	resp, err := n.Request().Get(ctx, &api.GetRequest{Key: key})
 	if err != nil {
-		return "", asClientError(err)
+		return nil, 0, asClientError(err)
 	}
 
-	return resp.Value, nil
+	return resp.Value, resp.Version, nil
 }
 
-func (c *client) Put(key, value string) error {
-	shard := c.algo.GetShard(key)
-	if shard == nil {
+func (c *client) Put(key string, value []byte, opts ...PutOption) error {
+	n := c.target(key)
+	if n == nil {
 		return ErrCacheMiss
 	}
 
-	n := c.nodesConfig.GetNode(shard.ID)
+	release := c.track(n)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := n.Request().Put(ctx, newPutRequest(key, value, opts))
+	if err != nil {
+		return fmt.Errorf("failed to put value in cache: %w", err)
+	}
+
+	c.invalidate(key, n)
+	return nil
+}
+
+func (c *client) CompareAndSwap(
+	key string, expectedVersion uint64, value []byte, opts ...PutOption,
+) (uint64, error) {
+	n := c.target(key)
+	if n == nil {
+		return 0, ErrCacheMiss
+	}
+
+	release := c.track(n)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	put := newPutRequest(key, value, opts)
+	resp, err := n.Request().CompareAndSwap(ctx, &api.CompareAndSwapRequest{
+		Key:             key,
+		ExpectedVersion: expectedVersion,
+		Value:           put.Value,
+		Ttl:             put.Ttl,
+	})
+	if err != nil {
+		return 0, asClientError(err)
+	}
+
+	c.invalidate(key, n)
+	return resp.Version, nil
+}
+
+func (c *client) Incr(key string, delta int64, opts ...PutOption) (int64, error) {
+	n := c.target(key)
+	if n == nil {
+		return 0, ErrCacheMiss
+	}
+
+	release := c.track(n)
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := n.Request().Incr(ctx, &api.IncrRequest{
+		Key:   key,
+		Delta: delta,
+		Ttl:   newPutRequest(key, nil, opts).Ttl,
+	})
+	if err != nil {
+		return 0, asClientError(err)
+	}
+
+	c.invalidate(key, n)
+	return resp.Value, nil
+}
+
+func (c *client) Decr(key string, delta int64, opts ...PutOption) (int64, error) {
+	// -math.MinInt64 overflows to itself.
+	if delta == math.MinInt64 {
+		return 0, ErrOverflow
+	}
+
+	return c.Incr(key, -delta, opts...)
+}
+
+func (c *client) Delete(key string) error {
+	n := c.target(key)
 	if n == nil {
-		zap.L().Info("sharding and nodes config are not synced, got outdated shard")
 		return ErrCacheMiss
 	}
 
+	// the diverted key may be left on its owner by the writes before the
+	// diversion, so it's deleted from both nodes.
+	err := c.delete(n, key)
+	owner := c.owner(key)
+	if err != ErrCacheMiss || owner == nil || owner.ID == n.ID {
+		if err == nil {
+			c.invalidate(key, n)
+		}
+
+		return err
+	}
+
+	return c.delete(owner, key)
+}
+
+func (c *client) delete(n *node.Node, key string) error {
+	release := c.track(n)
+	defer release()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err := n.Request().Put(ctx, &api.PutRequest{Key: key, Value: value})
+	_, err := n.Request().Delete(ctx, &api.DeleteRequest{Key: key})
 	if err != nil {
-		return fmt.Errorf("failed to put value in cache: %w", err)
+		return asClientError(err)
 	}
 
 	return nil
 }
 
+func (c *client) topologyChanged() <-chan struct{} {
+	c.topologyMx.Lock()
+	defer c.topologyMx.Unlock()
+
+	return c.topology
+}
+
+func (c *client) notifyTopologyChanged() {
+	c.topologyMx.Lock()
+	defer c.topologyMx.Unlock()
+
+	close(c.topology)
+	c.topology = make(chan struct{})
+}
+
 func (c *client) SyncClusterConfig(ctx context.Context) <-chan error {
 	errCh := make(chan error, c.errChSize)
 
@@ -149,6 +394,7 @@ func (c *client) SyncClusterConfig(ctx context.Context) <-chan error {
 
 				zap.L().Debug("nodes config is changed, syncing shards")
 				sharding.SyncShards(c.algo, c.nodesConfig.GetShards())
+				c.notifyTopologyChanged()
 			}
 		}
 	}()
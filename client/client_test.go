@@ -11,15 +11,18 @@ import (
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"log"
+	"math"
 	"net"
 	"os"
 	"sync"
 	"testing"
+	"time"
 )
 
 const (
 	defaultCacheCapacity = 2000
 	defaultServerPort    = 50051
+	adminToken           = "secret"
 
 	singleNodeConfig = `
 nodes:
@@ -63,8 +66,13 @@ func withTemporaryFile(t *testing.T, content string) (path string, cleanup func(
 }
 
 func upServer(ctx context.Context, wg *sync.WaitGroup, t *testing.T, port int) error {
-	s := grpc.NewServer()
-	cacheServer := server.NewCacheServer("", eviction.NewLRU(defaultCacheCapacity))
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(server.AdminInterceptor(adminToken)))
+	watches := server.NewWatchRegistry()
+	cacheServer := server.NewCacheServer(
+		"",
+		eviction.NewLRU(defaultCacheCapacity, eviction.WithListener(watches.OnRemove)),
+		server.WithWatchRegistry(watches),
+	)
 	api.RegisterCacheServiceServer(s, cacheServer)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
@@ -108,25 +116,158 @@ func TestClient_Flow(t *testing.T) {
 		{
 			name: "cache hit",
 			pre: func(c Client) {
-				require.NoError(t, c.Put("key", "value"))
+				require.NoError(t, c.Put("key", []byte("value")))
 			},
 			verify: func(c Client) {
 				v, e := c.Get("key")
+				require.Equal(t, []byte("value"), v)
+				require.NoError(t, e)
+			},
+		},
+		{
+			name: "delete",
+			pre: func(c Client) {
+				require.NoError(t, c.Put("key", []byte("value")))
+				require.NoError(t, c.Delete("key"))
+			},
+			verify: func(c Client) {
+				_, e := c.Get("key")
+				require.Equal(t, ErrCacheMiss, e)
+				require.Equal(t, ErrCacheMiss, c.Delete("key"))
+			},
+		},
+		{
+			name: "expired key",
+			pre: func(c Client) {
+				require.NoError(t, c.Put("key", []byte("value"), WithTTL(50*time.Millisecond)))
+				v, e := c.Get("key")
+				require.Equal(t, []byte("value"), v)
+				require.NoError(t, e)
+
+				time.Sleep(100 * time.Millisecond)
+			},
+			verify: func(c Client) {
+				_, e := c.Get("key")
+				require.Equal(t, ErrCacheMiss, e)
+			},
+		},
+		{
+			name: "batch",
+			pre: func(c Client) {
+				require.NoError(t, c.MPut(map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2")}))
+			},
+			verify: func(c Client) {
+				values, e := c.MGet([]string{"k1", "k2", "k3"})
+				require.NoError(t, e)
+				require.Equal(t, map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2")}, values)
+			},
+		},
+		{
+			name: "batch without owner nodes",
+			pre:  func(c Client) {},
+			verify: func(c Client) {
+				cl := c.(*client)
+				shards := cl.algo.GetShards()
+				for _, shard := range shards {
+					require.NoError(t, cl.algo.DeleteShard(shard))
+				}
+
+				defer func() {
+					for _, shard := range shards {
+						require.NoError(t, cl.algo.RegisterShard(shard))
+					}
+				}()
+
+				expected := &BatchError{Errors: map[string]error{"k1": ErrCacheMiss, "k2": ErrCacheMiss}}
+
+				values, e := c.MGet([]string{"k1", "k2"})
+				require.Equal(t, expected, e)
+				require.Empty(t, values)
+
+				e = c.MPut(map[string][]byte{"k1": []byte("v1"), "k2": []byte("v2")})
+				require.Equal(t, expected, e)
+			},
+		},
+		{
+			name: "string client compatibility",
+			pre: func(c Client) {
+				require.NoError(t, NewStringClient(c).Put("string", "value"))
+			},
+			verify: func(c Client) {
+				v, e := NewStringClient(c).Get("string")
 				require.Equal(t, "value", v)
 				require.NoError(t, e)
+
+				raw, e := c.Get("string")
+				require.Equal(t, []byte("value"), raw)
+				require.NoError(t, e)
+			},
+		},
+		{
+			name: "compare and swap",
+			pre: func(c Client) {
+				require.NoError(t, c.Put("counter", []byte("1")))
+			},
+			verify: func(c Client) {
+				v, version, e := c.GetWithVersion("counter")
+				require.NoError(t, e)
+				require.Equal(t, []byte("1"), v)
+
+				next, e := c.CompareAndSwap("counter", version, []byte("2"))
+				require.NoError(t, e)
+				require.Greater(t, next, version)
+
+				_, e = c.CompareAndSwap("counter", version, []byte("3"))
+				require.Equal(t, ErrVersionConflict, e)
+
+				_, e = c.CompareAndSwap("counter", 0, []byte("3"))
+				require.Equal(t, ErrVersionConflict, e)
+
+				v, e = c.Get("counter")
+				require.NoError(t, e)
+				require.Equal(t, []byte("2"), v)
+			},
+		},
+		{
+			name: "incr and decr",
+			pre: func(c Client) {
+				var wg sync.WaitGroup
+				for i := 0; i < 50; i++ {
+					wg.Add(1)
+
+					go func() {
+						defer wg.Done()
+
+						_, e := c.Incr("hits", 2)
+						require.NoError(t, e)
+					}()
+				}
+
+				wg.Wait()
+			},
+			verify: func(c Client) {
+				v, e := c.Decr("hits", 1)
+				require.NoError(t, e)
+				require.Equal(t, int64(99), v)
+
+				_, e = c.Decr("hits", math.MinInt64)
+				require.Equal(t, ErrOverflow, e)
+
+				_, e = c.Incr("string", 1)
+				require.Equal(t, ErrNotInteger, e)
 			},
 		},
 		{
 			name: "cache 1000 keys",
 			pre: func(c Client) {
 				for i := 0; i < defaultCacheCapacity/2; i++ {
-					require.NoError(t, c.Put(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i)))
+					require.NoError(t, c.Put(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i))))
 				}
 			},
 			verify: func(c Client) {
 				for i := 0; i < defaultCacheCapacity/2; i++ {
 					v, e := c.Get(fmt.Sprintf("key%d", i))
-					require.Equal(t, fmt.Sprintf("value%d", i), v)
+					require.Equal(t, []byte(fmt.Sprintf("value%d", i)), v)
 					require.NoError(t, e)
 				}
 			},
@@ -135,7 +276,7 @@ func TestClient_Flow(t *testing.T) {
 			name: "out of capacity",
 			pre: func(c Client) {
 				for i := 0; i < defaultCacheCapacity*2; i++ {
-					require.NoError(t, c.Put(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i)))
+					require.NoError(t, c.Put(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i))))
 				}
 			},
 			verify: func(c Client) {
@@ -180,6 +321,10 @@ func TestClient_Flow(t *testing.T) {
 func TestClient_MultipleNodes(t *testing.T) {
 	var (
 		nodes = 3
+
+		// admin is the client with the admin token, it's created for every
+		// algorithm together with the regular one.
+		admin Client
 	)
 
 	testcases := []struct {
@@ -187,12 +332,11 @@ func TestClient_MultipleNodes(t *testing.T) {
 		pre    func(c Client)
 		verify func(c Client)
 	}{
-		// fixme: don't work with consistent, probably bad circle config
 		{
 			name: "as in single node out of capacity",
 			pre: func(c Client) {
 				for i := 0; i < defaultCacheCapacity*2; i++ {
-					require.NoError(t, c.Put(fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i)))
+					require.NoError(t, c.Put(fmt.Sprintf("key%d", i), []byte(fmt.Sprintf("value%d", i))))
 				}
 			},
 			verify: func(c Client) {
@@ -202,13 +346,381 @@ func TestClient_MultipleNodes(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "scan across nodes",
+			pre: func(c Client) {
+				for i := 0; i < 100; i++ {
+					require.NoError(t, c.Put(fmt.Sprintf("scan:%03d", i), []byte(fmt.Sprintf("value%d", i))))
+				}
+			},
+			verify: func(c Client) {
+				var (
+					cursor string
+					keys   = make([]string, 0, 100)
+				)
+
+				for {
+					items, next, e := c.Scan("scan:", cursor, 7, WithValues())
+					require.NoError(t, e)
+					require.LessOrEqual(t, len(items), 7)
+
+					for _, item := range items {
+						require.Equal(t, []byte(fmt.Sprintf("value%d", len(keys))), item.Value)
+						keys = append(keys, item.Key)
+					}
+
+					if next == "" {
+						break
+					}
+
+					cursor = next
+				}
+
+				require.Len(t, keys, 100)
+				for i, key := range keys {
+					require.Equal(t, fmt.Sprintf("scan:%03d", i), key)
+				}
+			},
+		},
+		{
+			name: "cluster stats",
+			pre: func(c Client) {
+				require.NoError(t, c.Put("stats", []byte("value")))
+				_, e := c.Get("stats")
+				require.NoError(t, e)
+				_, e = c.Get("missing")
+				require.Equal(t, ErrCacheMiss, e)
+			},
+			verify: func(c Client) {
+				cs := c.ClusterStats()
+				require.Empty(t, cs.Failed)
+				require.Len(t, cs.Nodes, nodes)
+
+				var sum Stats
+				for _, s := range cs.Nodes {
+					sum = sum.add(s)
+				}
+
+				require.Equal(t, sum, cs.Total)
+				require.Equal(t, uint64(nodes*defaultCacheCapacity), cs.Total.Capacity)
+				require.Equal(t, cs.Total.Gets, cs.Total.Hits+cs.Total.Misses)
+				require.NotZero(t, cs.Total.Misses)
+				require.NotZero(t, cs.Total.Puts)
+				require.NotZero(t, cs.Total.Bytes)
+				require.Greater(t, cs.Total.Memory, cs.Total.Bytes)
+				require.Greater(t, cs.Total.HitRatio(), 0.0)
+			},
+		},
+		{
+			name: "flush",
+			pre: func(c Client) {
+				require.NoError(t, c.MPut(map[string][]byte{
+					"flush:1": []byte("value"),
+					"flush:2": []byte("value"),
+					"flush:3": []byte("value"),
+				}))
+			},
+			verify: func(c Client) {
+				for _, r := range c.Flush("flush:") {
+					require.Equal(t, ErrUnauthorized, r.Err)
+				}
+
+				var removed uint64
+				flushed := admin.Flush("flush:")
+				require.Len(t, flushed, nodes)
+				for _, r := range flushed {
+					require.NoError(t, r.Err)
+					removed += r.Removed
+				}
+
+				require.Equal(t, uint64(3), removed)
+				values, e := c.MGet([]string{"flush:1", "flush:2", "flush:3"})
+				require.NoError(t, e)
+				require.Empty(t, values)
+			},
+		},
+		{
+			name: "batch across nodes",
+			pre: func(c Client) {
+				items := make(map[string][]byte, defaultCacheCapacity)
+				for i := 0; i < defaultCacheCapacity; i++ {
+					items[fmt.Sprintf("batch%d", i)] = []byte(fmt.Sprintf("value%d", i))
+				}
+
+				require.NoError(t, c.MPut(items))
+			},
+			verify: func(c Client) {
+				keys := make([]string, 0, defaultCacheCapacity)
+				for i := 0; i < defaultCacheCapacity; i++ {
+					keys = append(keys, fmt.Sprintf("batch%d", i))
+				}
+
+				values, e := c.MGet(keys)
+				require.NoError(t, e)
+				require.Equal(t, defaultCacheCapacity, len(values))
+				for i := 0; i < defaultCacheCapacity; i++ {
+					require.Equal(t, []byte(fmt.Sprintf("value%d", i)), values[fmt.Sprintf("batch%d", i)])
+				}
+			},
+		},
 	}
 
+	path, cleanup := withTemporaryFile(t, multipleNodesConfig)
+	defer cleanup()
+
+	for _, algo := range []sharding.AlgorithmType{
+		sharding.RendezvousAlgorithm,
+		sharding.ConsistentAlgorithm,
+		sharding.BoundedLoadAlgorithm,
+	} {
+		t.Run(string(algo), func(t *testing.T) {
+			var (
+				wg          sync.WaitGroup
+				ctx, cancel = context.WithCancel(context.Background())
+			)
+
+			for i := 0; i < nodes; i++ {
+				wg.Add(1)
+				require.NoError(t, upServer(ctx, &wg, t, defaultServerPort+i))
+			}
+
+			c, err := NewClient(path, algo)
+			require.NoError(t, err)
+
+			admin, err = NewClient(path, algo, WithAdminToken(adminToken))
+			require.NoError(t, err)
+
+			for _, tc := range testcases {
+				t.Run(tc.name, func(t *testing.T) {
+					tc.pre(c)
+					tc.verify(c)
+				})
+			}
+
+			cancel()
+			wg.Wait()
+		})
+	}
+}
+
+func TestClient_BatchPartialFailure(t *testing.T) {
 	var (
 		wg          sync.WaitGroup
 		ctx, cancel = context.WithCancel(context.Background())
 	)
 
+	// only the first node of the config is up, the rest are unreachable.
+	wg.Add(1)
+	require.NoError(t, upServer(ctx, &wg, t, defaultServerPort))
+
+	path, cleanup := withTemporaryFile(t, multipleNodesConfig)
+	defer cleanup()
+
+	c, err := NewClient(path, sharding.RendezvousAlgorithm)
+	require.NoError(t, err)
+
+	var (
+		items = make(map[string][]byte, 100)
+		keys  = make([]string, 0, 100)
+	)
+
+	for i := 0; i < 100; i++ {
+		items[fmt.Sprintf("key%d", i)] = []byte(fmt.Sprintf("value%d", i))
+		keys = append(keys, fmt.Sprintf("key%d", i))
+	}
+
+	var batchErr *BatchError
+	require.ErrorAs(t, c.MPut(items), &batchErr)
+	require.NotEmpty(t, batchErr.Errors)
+	require.Less(t, len(batchErr.Errors), len(items))
+
+	values, err := c.MGet(keys)
+	require.ErrorAs(t, err, &batchErr)
+	require.Equal(t, len(keys), len(values)+len(batchErr.Errors))
+	for key, v := range values {
+		require.Equal(t, items[key], v)
+		require.NotContains(t, batchErr.Errors, key)
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestClient_BoundedLoadDiversion(t *testing.T) {
+	var (
+		wg          sync.WaitGroup
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		require.NoError(t, upServer(ctx, &wg, t, defaultServerPort+i))
+	}
+
+	path, cleanup := withTemporaryFile(t, multipleNodesConfig)
+	defer cleanup()
+
+	c, err := NewClient(path, sharding.BoundedLoadAlgorithm)
+	require.NoError(t, err)
+
+	var (
+		algo    = c.(*client).algo
+		tracker = algo.(sharding.LoadTracker)
+		owner   = algo.GetShard("hot")
+	)
+
+	require.NoError(t, c.Put("hot", []byte("old")))
+	require.NoError(t, c.Put("deleted", []byte("old")))
+
+	// in-flight requests of other goroutines overload the owner of the key.
+	for i := 0; i < 10; i++ {
+		tracker.Acquire(owner)
+	}
+	require.NotEqual(t, owner.ID, algo.GetShard("hot").ID)
+
+	// the diverted key is written to the next node and read from it.
+	require.NoError(t, c.Put("hot", []byte("new")))
+	val, err := c.Get("hot")
+	require.NoError(t, err)
+	require.Equal(t, []byte("new"), val)
+
+	for i := 0; i < 10; i++ {
+		tracker.Release(owner)
+	}
+
+	// the owner doesn't return the old value, when the load is gone.
+	_, err = c.Get("hot")
+	require.Equal(t, ErrCacheMiss, err)
+
+	// the key, which is left on the owner, is deleted under the load too.
+	deletedOwner := algo.GetShard("deleted")
+	for i := 0; i < 10; i++ {
+		tracker.Acquire(deletedOwner)
+	}
+
+	require.NoError(t, c.Delete("deleted"))
+	require.Equal(t, ErrCacheMiss, c.Delete("deleted"))
+
+	for i := 0; i < 10; i++ {
+		tracker.Release(deletedOwner)
+	}
+
+	_, err = c.Get("deleted")
+	require.Equal(t, ErrCacheMiss, err)
+
+	cancel()
+	wg.Wait()
+}
+
+func nextEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "events channel is closed")
+		return e
+	case <-time.After(time.Second):
+		require.FailNow(t, "no event received")
+		return WatchEvent{}
+	}
+}
+
+func TestClient_Watch(t *testing.T) {
+	var (
+		wg          sync.WaitGroup
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+
+	wg.Add(1)
+	require.NoError(t, upServer(ctx, &wg, t, defaultServerPort))
+
+	path, cleanup := withTemporaryFile(t, singleNodeConfig)
+	defer cleanup()
+
+	c, err := NewClient(path, sharding.RendezvousAlgorithm)
+	require.NoError(t, err)
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	events := c.Watch(watchCtx, "config")
+
+	require.NoError(t, c.Put("other", []byte("value")))
+	require.NoError(t, c.Put("config", []byte("v1")))
+	e := nextEvent(t, events)
+	require.Equal(t, EventPut, e.Type)
+	require.Equal(t, "config", e.Key)
+	require.Equal(t, []byte("v1"), e.Value)
+
+	require.NoError(t, c.Delete("config"))
+	require.Equal(t, WatchEvent{Type: EventDelete, Key: "config"}, nextEvent(t, events))
+
+	require.NoError(t, c.Put("config", []byte("v2"), WithTTL(10*time.Millisecond)))
+	require.Equal(t, EventPut, nextEvent(t, events).Type)
+
+	time.Sleep(20 * time.Millisecond)
+	_, err = c.Get("config")
+	require.Equal(t, ErrCacheMiss, err)
+	require.Equal(t, WatchEvent{Type: EventExpire, Key: "config"}, nextEvent(t, events))
+
+	// events of the concurrent puts come in the order of the versions, so
+	// the watcher ends with the stored value.
+	var puts sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		puts.Add(1)
+
+		go func(i int) {
+			defer puts.Done()
+			require.NoError(t, c.Put("config", []byte(fmt.Sprintf("v%d", i))))
+		}(i)
+	}
+
+	puts.Wait()
+
+	var last WatchEvent
+	for i := 0; i < 32; i++ {
+		e = nextEvent(t, events)
+		require.Greater(t, e.Version, last.Version)
+		last = e
+	}
+
+	val, version, err := c.GetWithVersion("config")
+	require.NoError(t, err)
+	require.Equal(t, val, last.Value)
+	require.Equal(t, version, last.Version)
+
+	// the key is evicted by the puts of other keys, its eviction is never
+	// published after the put, which stores the key again.
+	items := make(map[string][]byte, defaultCacheCapacity)
+	for i := 0; i < defaultCacheCapacity; i++ {
+		items[fmt.Sprintf("fill%d", i)] = []byte("value")
+	}
+
+	require.NoError(t, c.MPut(items))
+	require.NoError(t, c.Put("config", []byte("back")))
+
+	for e = nextEvent(t, events); e.Type == EventEvict; e = nextEvent(t, events) {
+	}
+
+	require.Equal(t, EventPut, e.Type)
+	require.Equal(t, []byte("back"), e.Value)
+	select {
+	case e = <-events:
+		require.Failf(t, "unexpected event", "%+v", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	stopWatch()
+	for range events {
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestClient_WatchMultipleNodes(t *testing.T) {
+	var (
+		nodes       = 3
+		wg          sync.WaitGroup
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+
 	for i := 0; i < nodes; i++ {
 		wg.Add(1)
 		require.NoError(t, upServer(ctx, &wg, t, defaultServerPort+i))
@@ -220,13 +732,164 @@ func TestClient_MultipleNodes(t *testing.T) {
 	c, err := NewClient(path, sharding.RendezvousAlgorithm)
 	require.NoError(t, err)
 
+	t.Run("prefix across nodes", func(t *testing.T) {
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+
+		events := c.Watch(watchCtx, "cfg:", WithPrefix())
+
+		var expected = make(map[string]struct{}, 30)
+		for i := 0; i < 30; i++ {
+			key := fmt.Sprintf("cfg:%d", i)
+			expected[key] = struct{}{}
+			require.NoError(t, c.Put(key, []byte("value")))
+		}
+
+		require.NoError(t, c.Put("other", []byte("value")))
+
+		var received = make(map[string]struct{}, 30)
+		for i := 0; i < 30; i++ {
+			received[nextEvent(t, events).Key] = struct{}{}
+		}
+
+		require.Equal(t, expected, received)
+	})
+
+	t.Run("flush publishes deletes", func(t *testing.T) {
+		admin, e := NewClient(path, sharding.RendezvousAlgorithm, WithAdminToken(adminToken))
+		require.NoError(t, e)
+
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+
+		events := c.Watch(watchCtx, "flushed:", WithPrefix())
+
+		var expected = make(map[string]struct{}, 10)
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("flushed:%d", i)
+			expected[key] = struct{}{}
+			require.NoError(t, c.Put(key, []byte("value")))
+			require.Equal(t, EventPut, nextEvent(t, events).Type)
+		}
+
+		for _, r := range admin.Flush("flushed:") {
+			require.NoError(t, r.Err)
+		}
+
+		var deleted = make(map[string]struct{}, 10)
+		for i := 0; i < 10; i++ {
+			ev := nextEvent(t, events)
+			require.Equal(t, EventDelete, ev.Type)
+			deleted[ev.Key] = struct{}{}
+		}
+
+		require.Equal(t, expected, deleted)
+	})
+
+	t.Run("resubscribe on ownership change", func(t *testing.T) {
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+
+		var (
+			cl     = c.(*client)
+			events = c.Watch(watchCtx, "moving")
+			owner  = cl.algo.GetShard("moving")
+		)
+
+		// simulating the sync of the cluster config, where the owner of
+		// the key is removed from the cluster.
+		require.NoError(t, cl.algo.DeleteShard(owner))
+		defer func() { require.NoError(t, cl.algo.RegisterShard(owner)) }()
+		cl.notifyTopologyChanged()
+
+		require.NotEqual(t, owner.ID, cl.algo.GetShard("moving").ID)
+		require.Eventually(t, func() bool {
+			if e := c.Put("moving", []byte("value")); e != nil {
+				return false
+			}
+
+			select {
+			case e := <-events:
+				return e.Key == "moving"
+			case <-time.After(10 * time.Millisecond):
+				return false
+			}
+		}, time.Second, time.Millisecond)
+	})
+
+	cancel()
+	wg.Wait()
+}
+
+func TestMergeScanPages(t *testing.T) {
+	kv := func(keys ...string) []*api.KeyValue {
+		items := make([]*api.KeyValue, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, &api.KeyValue{Key: key})
+		}
+
+		return items
+	}
+
+	testcases := []struct {
+		name     string
+		pages    map[string]scanPage
+		limit    int
+		expected []string
+		next     string
+	}{
+		{
+			name: "all exhausted",
+			pages: map[string]scanPage{
+				"1": {items: kv("a", "c")},
+				"2": {items: kv("b")},
+			},
+			limit:    10,
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name: "bounded by the shortest unfinished page",
+			pages: map[string]scanPage{
+				"1": {items: kv("a", "b"), next: "b"},
+				"2": {items: kv("c", "d"), next: "d"},
+				"3": {items: kv("e")},
+			},
+			limit:    10,
+			expected: []string{"a", "b"},
+			next:     "b",
+		},
+		{
+			name: "truncated by limit",
+			pages: map[string]scanPage{
+				"1": {items: kv("a", "c")},
+				"2": {items: kv("b", "d")},
+			},
+			limit:    3,
+			expected: []string{"a", "b", "c"},
+			next:     "c",
+		},
+		{
+			name: "duplicated keys",
+			pages: map[string]scanPage{
+				"1": {items: kv("a", "b")},
+				"2": {items: kv("a")},
+			},
+			limit:    10,
+			expected: []string{"a", "b"},
+		},
+	}
+
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			tc.pre(c)
-			tc.verify(c)
+			items, next := mergeScanPages(tc.pages, tc.limit)
+			require.Equal(t, tc.next, next)
+
+			keys := make([]string, 0, len(items))
+			for _, item := range items {
+				keys = append(keys, item.Key)
+			}
+
+			require.Equal(t, tc.expected, keys)
 		})
 	}
-
-	cancel()
-	wg.Wait()
 }
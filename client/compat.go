@@ -0,0 +1,48 @@
+package client
+
+// StringClient adapts Client to the string values, which were used before
+// values became binary-safe, Delete and SyncClusterConfig are promoted
+// from the wrapped Client as is.
+//
+// Deprecated: use Client with []byte values, strings can be converted
+// at the call site.
+type StringClient struct {
+	Client
+}
+
+func NewStringClient(c Client) *StringClient {
+	return &StringClient{Client: c}
+}
+
+func (s *StringClient) Get(key string) (string, error) {
+	val, err := s.Client.Get(key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(val), nil
+}
+
+func (s *StringClient) Put(key, value string, opts ...PutOption) error {
+	return s.Client.Put(key, []byte(value), opts...)
+}
+
+func (s *StringClient) MGet(keys []string) (map[string]string, error) {
+	values, err := s.Client.MGet(keys)
+
+	var converted = make(map[string]string, len(values))
+	for k, v := range values {
+		converted[k] = string(v)
+	}
+
+	return converted, err
+}
+
+func (s *StringClient) MPut(items map[string]string, opts ...PutOption) error {
+	var converted = make(map[string][]byte, len(items))
+	for k, v := range items {
+		converted[k] = []byte(v)
+	}
+
+	return s.Client.MPut(converted, opts...)
+}
@@ -6,8 +6,81 @@ import (
 
 // Client is the interface that wraps the basic methods of a cache client.
 type Client interface {
-	Get(key string) (string, error)
-	Put(key, value string) error
+	Get(key string) ([]byte, error)
+	Put(key string, value []byte, opts ...PutOption) error
+
+	// GetWithVersion returns the value and its version, which can be used
+	// as the expected one in CompareAndSwap.
+	GetWithVersion(key string) ([]byte, uint64, error)
+
+	// CompareAndSwap writes the value only if the key wasn't changed since
+	// the expected version was read, zero version means that the key must
+	// not exist.
+	// - On success, it returns the new version of the key.
+	// - On conflict, ErrVersionConflict is returned.
+	CompareAndSwap(key string, expectedVersion uint64, value []byte, opts ...PutOption) (uint64, error)
+
+	// MGet returns values of the given keys, requesting each node, which
+	// owns some of the keys, in parallel.
+	// - Missing keys are omitted from the result.
+	// - If some of the nodes are failed, *BatchError is returned with an
+	//   error for every key of the failed nodes.
+	// - Keys without the owner node are reported as ErrCacheMiss in
+	//   *BatchError, as in MPut.
+	MGet(keys []string) (map[string][]byte, error)
+
+	// MPut is the batched version of Put, it groups items by the nodes,
+	// which own them, and writes them in parallel.
+	// - If some of the nodes are failed, *BatchError is returned with an
+	//   error for every key, which wasn't written.
+	// - Keys without the owner node are reported as ErrCacheMiss.
+	MPut(items map[string][]byte, opts ...PutOption) error
+
+	// Incr atomically adds delta to the integer value of the key on the node,
+	// which owns it, and returns the result.
+	// - If the key doesn't exist, it's created with the delta value, WithTTL
+	//   is applied only in this case.
+	// - If the value isn't an integer, ErrNotInteger is returned.
+	// - If the result overflows int64, ErrOverflow is returned.
+	Incr(key string, delta int64, opts ...PutOption) (int64, error)
+
+	// Decr is the same as Incr with the negated delta, math.MinInt64 can't
+	// be negated, so ErrOverflow is returned for it.
+	Decr(key string, delta int64, opts ...PutOption) (int64, error)
+
+	// Delete removes the key from the node, which owns it.
+	// - If the key doesn't exist, ErrCacheMiss is returned.
+	Delete(key string) error
+
+	// Scan returns up to limit keys with the prefix, which are greater than
+	// the cursor, in the lexicographical order, walking all nodes of the
+	// cluster.
+	// - The returned cursor is passed to the next call, empty cursor means
+	//   the beginning for the first call, and the end for the returned one.
+	// - Keys, which exist during the whole scan, are returned exactly once,
+	//   keys added or removed in between may be missed.
+	Scan(prefix, cursor string, limit int, opts ...ScanOption) ([]Item, string, error)
+
+	// ClusterStats requests stats of every node in the cluster config in
+	// parallel and sums them up.
+	ClusterStats() ClusterStats
+
+	// Flush removes keys with the prefix from every node in the cluster
+	// config, empty prefix removes all keys, results are keyed by node IDs.
+	// - It's an admin call, so the client must be created WithAdminToken,
+	//   otherwise nodes reject it with ErrUnauthorized.
+	Flush(prefix string) map[string]FlushResult
+
+	// Watch streams changes of the key from the node, which owns it, until
+	// the context is done, then the channel is closed.
+	// - With WithPrefix, all keys with the prefix are watched on every node.
+	// - When the cluster config is changed by SyncClusterConfig, the key is
+	//   watched on its new owner.
+	// - Broken streams are re-established, changes made in between are lost.
+	// - With sharding.BoundedLoadAlgorithm, writes, diverted from the
+	//   overloaded owner, are seen as DELETE, because the key is removed
+	//   from the owner.
+	Watch(ctx context.Context, key string, opts ...WatchOption) <-chan WatchEvent
 
 	// SyncClusterConfig under the hood, periodically goes to the server and
 	// fetches the latest cluster configuration.
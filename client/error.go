@@ -2,12 +2,22 @@ package client
 
 import (
 	"errors"
+	"fmt"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 var (
 	ErrCacheMiss = errors.New("cache miss")
+
+	// ErrVersionConflict is returned, when the key was changed by someone
+	// else since it was read, the caller should re-read the key and retry.
+	ErrVersionConflict = errors.New("version conflict")
+
+	ErrUnauthorized = errors.New("admin token is missing or invalid")
+
+	ErrNotInteger = errors.New("value is not an integer")
+	ErrOverflow   = errors.New("increment or decrement would overflow")
 )
 
 func asClientError(err error) error {
@@ -19,7 +29,33 @@ func asClientError(err error) error {
 	switch gstatus.Code() {
 	case codes.NotFound:
 		return ErrCacheMiss
+	case codes.Aborted:
+		return ErrVersionConflict
+	case codes.FailedPrecondition:
+		return ErrNotInteger
+	case codes.OutOfRange:
+		return ErrOverflow
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return ErrUnauthorized
 	default:
 		return err
 	}
 }
+
+// BatchError is returned by batch operations, when some of the keys are
+// failed, results for the rest of the keys are still returned.
+type BatchError struct {
+	Errors map[string]error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch operation failed for %d keys", len(e.Errors))
+}
+
+func asBatchError(errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &BatchError{Errors: errs}
+}
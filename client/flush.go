@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"github.com/fadyat/speedy/api"
+	"github.com/fadyat/speedy/node"
+	"google.golang.org/grpc/metadata"
+	"sync"
+	"time"
+)
+
+const (
+
+	// admin token is passed in the same way, as the server expects it.
+	authorizationHeader = "authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// FlushResult is the result of the flush on a single node.
+type FlushResult struct {
+	Removed uint64
+	Err     error
+}
+
+func (c *client) Flush(prefix string) map[string]FlushResult {
+	var (
+		wg      sync.WaitGroup
+		mx      sync.Mutex
+		results = make(map[string]FlushResult)
+	)
+
+	for _, n := range c.nodes() {
+		wg.Add(1)
+
+		go func(n *node.Node) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			ctx = metadata.AppendToOutgoingContext(
+				ctx, authorizationHeader, bearerPrefix+c.adminToken,
+			)
+
+			var result FlushResult
+			resp, err := n.Request().Flush(ctx, &api.FlushRequest{Prefix: prefix})
+			if err != nil {
+				result.Err = asClientError(err)
+			} else {
+				result.Removed = resp.Removed
+			}
+
+			mx.Lock()
+			defer mx.Unlock()
+
+			results[n.ID] = result
+		}(n)
+	}
+
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/fadyat/speedy/api"
+	"github.com/fadyat/speedy/node"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Item is the key-value pair returned by Scan, Value is set only when
+// WithValues is used.
+type Item struct {
+	Key   string
+	Value []byte
+}
+
+type scanOptions struct {
+	withValues bool
+}
+
+// ScanOption is used to configure a single Scan call.
+type ScanOption func(*scanOptions)
+
+// WithValues makes Scan to return values of the keys.
+func WithValues() ScanOption {
+	return func(o *scanOptions) {
+		o.withValues = true
+	}
+}
+
+// scanPage is the page of a single node.
+type scanPage struct {
+	items []*api.KeyValue
+	next  string
+}
+
+func (c *client) Scan(prefix, cursor string, limit int, opts ...ScanOption) ([]Item, string, error) {
+	var o scanOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mx    sync.Mutex
+		pages = make(map[string]scanPage)
+		errs  = make(map[string]error)
+		req   = &api.ScanRequest{
+			Prefix:     prefix,
+			Cursor:     cursor,
+			Limit:      uint32(limit),
+			WithValues: o.withValues,
+		}
+	)
+
+	// every node returns keys after the same cursor, keys are ordered in the
+	// same way on every node, so the merged page is continued from its
+	// last key, no matter which node owns it.
+	for _, n := range c.nodes() {
+		wg.Add(1)
+
+		go func(n *node.Node) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			resp, err := n.Request().Scan(ctx, req)
+
+			mx.Lock()
+			defer mx.Unlock()
+
+			if err != nil {
+				errs[n.ID] = err
+				return
+			}
+
+			pages[n.ID] = scanPage{items: resp.Items, next: resp.NextCursor}
+		}(n)
+	}
+
+	wg.Wait()
+	for id, err := range errs {
+		return nil, "", fmt.Errorf("failed to scan node %s: %w", id, err)
+	}
+
+	items, next := mergeScanPages(pages, limit)
+	return items, next, nil
+}
+
+// mergeScanPages merges pages of the nodes into the single one, ordered
+// by keys, the keys, which are stored on multiple nodes, are returned once.
+//
+// merged page can't go further than the shortest unfinished page of the
+// nodes, otherwise the keys of that node, which are between its last key
+// and the last key of the merged page, are skipped.
+func mergeScanPages(pages map[string]scanPage, limit int) ([]Item, string) {
+	var (
+		items     = make([]Item, 0)
+		seen      = make(map[string]struct{})
+		exhausted = true
+		bound     string
+	)
+
+	for _, page := range pages {
+		if page.next != "" && (exhausted || page.next < bound) {
+			bound = page.next
+			exhausted = false
+		}
+	}
+
+	for _, page := range pages {
+		for _, kv := range page.items {
+			if _, ok := seen[kv.Key]; ok || (!exhausted && kv.Key > bound) {
+				continue
+			}
+
+			seen[kv.Key] = struct{}{}
+			items = append(items, Item{Key: kv.Key, Value: kv.Value})
+		}
+	}
+
+	slices.SortFunc(items, func(a, b Item) int {
+		switch {
+		case a.Key < b.Key:
+			return -1
+		case a.Key > b.Key:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if limit > 0 && len(items) > limit {
+		items, exhausted = items[:limit], false
+	}
+
+	if exhausted || len(items) == 0 {
+		return items, ""
+	}
+
+	return items, items[len(items)-1].Key
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"github.com/fadyat/speedy/api"
+	"github.com/fadyat/speedy/node"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"sync"
+	"time"
+)
+
+// Stats are counters of a single node, or the sum of them for the cluster.
+type Stats struct {
+	Gets        uint64
+	Hits        uint64
+	Misses      uint64
+	Puts        uint64
+	Evictions   uint64
+	Expirations uint64
+	Items       uint64
+	Bytes       uint64
+	Capacity    uint64
+
+	// Memory is Bytes with the overhead of every key, it's limited by
+	// MaxBytes, zero MaxBytes means no limit.
+	Memory   uint64
+	MaxBytes uint64
+
+	// Uptime of the cluster is the uptime of the youngest node.
+	Uptime time.Duration
+}
+
+// HitRatio returns the share of gets, which found the key.
+func (s Stats) HitRatio() float64 {
+	if s.Gets == 0 {
+		return 0
+	}
+
+	return float64(s.Hits) / float64(s.Gets)
+}
+
+func newStats(r *api.StatsResponse) Stats {
+	return Stats{
+		Gets:        r.Gets,
+		Hits:        r.Hits,
+		Misses:      r.Misses,
+		Puts:        r.Puts,
+		Evictions:   r.Evictions,
+		Expirations: r.Expirations,
+		Items:       uint64(r.Items),
+		Bytes:       r.Bytes,
+		Capacity:    r.Capacity,
+		Memory:      r.Memory,
+		MaxBytes:    r.MaxBytes,
+		Uptime:      r.GetUptime().AsDuration(),
+	}
+}
+
+func (s Stats) add(other Stats) Stats {
+	uptime := other.Uptime
+	if s.Uptime != 0 && s.Uptime < uptime {
+		uptime = s.Uptime
+	}
+
+	return Stats{
+		Gets:        s.Gets + other.Gets,
+		Hits:        s.Hits + other.Hits,
+		Misses:      s.Misses + other.Misses,
+		Puts:        s.Puts + other.Puts,
+		Evictions:   s.Evictions + other.Evictions,
+		Expirations: s.Expirations + other.Expirations,
+		Items:       s.Items + other.Items,
+		Bytes:       s.Bytes + other.Bytes,
+		Capacity:    s.Capacity + other.Capacity,
+		Memory:      s.Memory + other.Memory,
+		MaxBytes:    s.MaxBytes + other.MaxBytes,
+		Uptime:      uptime,
+	}
+}
+
+// ClusterStats are stats of every node and their sum, nodes, which are
+// failed to respond, are not included in the sum.
+type ClusterStats struct {
+	Total  Stats
+	Nodes  map[string]Stats
+	Failed map[string]error
+}
+
+func (c *client) ClusterStats() ClusterStats {
+	var (
+		wg sync.WaitGroup
+		mx sync.Mutex
+		cs = ClusterStats{
+			Nodes:  make(map[string]Stats),
+			Failed: make(map[string]error),
+		}
+	)
+
+	for _, n := range c.nodes() {
+		wg.Add(1)
+
+		go func(n *node.Node) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			resp, err := n.Request().Stats(ctx, &emptypb.Empty{})
+
+			mx.Lock()
+			defer mx.Unlock()
+
+			if err != nil {
+				cs.Failed[n.ID] = err
+				return
+			}
+
+			stats := newStats(resp)
+			cs.Nodes[n.ID] = stats
+			cs.Total = cs.Total.add(stats)
+		}(n)
+	}
+
+	wg.Wait()
+	return cs
+}
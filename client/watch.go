@@ -0,0 +1,222 @@
+package client
+
+import (
+	"context"
+	"github.com/fadyat/speedy/api"
+	"github.com/fadyat/speedy/node"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+
+	// watchRetryPeriod is the delay before subscribing again, when the
+	// watch stream to the node is broken.
+	watchRetryPeriod = time.Second
+
+	watchBufferSize = 64
+)
+
+type EventType int
+
+const (
+	EventPut EventType = iota
+	EventDelete
+	EventEvict
+	EventExpire
+)
+
+// WatchEvent is the change of the watched key, Value and Version are set
+// only for EventPut.
+type WatchEvent struct {
+	Type    EventType
+	Key     string
+	Value   []byte
+	Version uint64
+}
+
+func newWatchEvent(e *api.WatchEvent) WatchEvent {
+	var eventType = EventPut
+	switch e.Type {
+	case api.WatchEvent_DELETE:
+		eventType = EventDelete
+	case api.WatchEvent_EVICT:
+		eventType = EventEvict
+	case api.WatchEvent_EXPIRE:
+		eventType = EventExpire
+	case api.WatchEvent_PUT:
+	}
+
+	return WatchEvent{Type: eventType, Key: e.Key, Value: e.Value, Version: e.Version}
+}
+
+type watchOptions struct {
+	prefix bool
+}
+
+// WatchOption is used to configure a single Watch call.
+type WatchOption func(*watchOptions)
+
+// WithPrefix makes Watch to treat the key as a prefix, such keys can be
+// owned by any node, so all nodes of the cluster are watched.
+func WithPrefix() WatchOption {
+	return func(o *watchOptions) {
+		o.prefix = true
+	}
+}
+
+// watcher keeps watch streams to the nodes, which may own the watched
+// keys, and moves them, when the cluster config is changed.
+type watcher struct {
+	c       *client
+	req     *api.WatchRequest
+	events  chan WatchEvent
+	streams map[*node.Node]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func (c *client) Watch(ctx context.Context, key string, opts ...WatchOption) <-chan WatchEvent {
+	var o watchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w := &watcher{
+		c:       c,
+		req:     &api.WatchRequest{Key: key, Prefix: o.prefix},
+		events:  make(chan WatchEvent, watchBufferSize),
+		streams: make(map[*node.Node]context.CancelFunc),
+	}
+
+	// waiting for the first subscriptions, so changes made after the Watch
+	// returns are not missed.
+	var (
+		changed = c.topologyChanged()
+		ready   sync.WaitGroup
+	)
+
+	w.rebalance(ctx, &ready)
+	ready.Wait()
+
+	go w.run(ctx, changed)
+	return w.events
+}
+
+func (w *watcher) run(ctx context.Context, changed <-chan struct{}) {
+	defer func() {
+		for _, cancel := range w.streams {
+			cancel()
+		}
+
+		w.wg.Wait()
+		close(w.events)
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			changed = w.c.topologyChanged()
+			w.rebalance(ctx, &sync.WaitGroup{})
+		}
+	}
+}
+
+// targets returns nodes, which may own the watched keys.
+func (w *watcher) targets() map[*node.Node]struct{} {
+	var targets = make(map[*node.Node]struct{})
+	if !w.req.Prefix {
+		if n := w.c.owner(w.req.Key); n != nil {
+			targets[n] = struct{}{}
+		}
+
+		return targets
+	}
+
+	for _, n := range w.c.nodes() {
+		targets[n] = struct{}{}
+	}
+
+	return targets
+}
+
+// rebalance closes streams to nodes, which don't own the watched keys
+// anymore, and opens streams to the new owners.
+func (w *watcher) rebalance(ctx context.Context, ready *sync.WaitGroup) {
+	targets := w.targets()
+	for n, cancel := range w.streams {
+		if _, ok := targets[n]; !ok {
+			cancel()
+			delete(w.streams, n)
+		}
+	}
+
+	for n := range targets {
+		if _, ok := w.streams[n]; ok {
+			continue
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		w.streams[n] = cancel
+
+		w.wg.Add(1)
+		ready.Add(1)
+
+		go func(n *node.Node) {
+			defer w.wg.Done()
+			w.stream(streamCtx, n, ready)
+		}(n)
+	}
+}
+
+// stream forwards events of the node, until the context is done,
+// subscribing again, when the stream is broken.
+func (w *watcher) stream(ctx context.Context, n *node.Node, ready *sync.WaitGroup) {
+	var once sync.Once
+	for {
+		err := w.forward(ctx, n, func() { once.Do(ready.Done) })
+		once.Do(ready.Done)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		zap.L().Warn("watch stream is broken, subscribing again",
+			zap.String("node", n.ID), zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryPeriod):
+		}
+	}
+}
+
+func (w *watcher) forward(ctx context.Context, n *node.Node, subscribed func()) error {
+	s, err := n.Request().Watch(ctx, w.req)
+	if err != nil {
+		return err
+	}
+
+	// server sends headers only after the subscription is registered.
+	if _, err = s.Header(); err != nil {
+		return err
+	}
+
+	subscribed()
+	for {
+		e, err := s.Recv()
+		if err != nil {
+			return err
+		}
+
+		select {
+		case w.events <- newWatchEvent(e):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -1,14 +1,52 @@
 package main
 
-import "github.com/ilyakaznacheev/cleanenv"
+import (
+	"errors"
+	"fmt"
+	"github.com/fadyat/speedy/eviction"
+	"github.com/fadyat/speedy/oplog"
+	"github.com/ilyakaznacheev/cleanenv"
+	"time"
+)
 
 type Config struct {
 	Server struct {
 		GrpcPort string `env:"GRPC_PORT" env-default:"8080"`
+
+		// AdminToken is required for admin calls, like Flush, they are
+		// disabled, when the token is empty.
+		AdminToken string `env:"ADMIN_TOKEN"`
 	}
 
 	Cache struct {
-		Capacity int `env:"CACHE_CAP" env-default:"1000"`
+		// Policy is the registered eviction policy of the cache, see
+		// eviction.Algorithms for the available ones.
+		Policy   eviction.AlgorithmType `env:"CACHE_POLICY" env-default:"lru"`
+		Capacity int                    `env:"CACHE_CAP" env-default:"1000"`
+
+		// MaxBytes limits the memory of keys, values and their overhead,
+		// zero means that only the number of keys is limited.
+		MaxBytes    uint64        `env:"CACHE_MAX_BYTES" env-default:"0"`
+		SweepPeriod time.Duration `env:"CACHE_SWEEP_PERIOD" env-default:"1s"`
+	}
+
+	Snapshot struct {
+		// Path is the file, where the cache is saved periodically and on
+		// shutdown, snapshots are disabled, when the path is empty.
+		Path   string        `env:"SNAPSHOT_PATH"`
+		Period time.Duration `env:"SNAPSHOT_PERIOD" env-default:"1m"`
+	}
+
+	OpLog struct {
+		// Path is the file, where writes are appended between snapshots,
+		// it replaces periodic snapshots with the log compaction, the log
+		// is disabled, when the path is empty.
+		Path string           `env:"OPLOG_PATH"`
+		Sync oplog.SyncPolicy `env:"OPLOG_SYNC" env-default:"everysec"`
+
+		// RewriteSize is the size of the log in bytes, after which it's
+		// compacted into the snapshot.
+		RewriteSize int64 `env:"OPLOG_REWRITE_SIZE" env-default:"67108864"`
 	}
 }
 
@@ -18,5 +56,17 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if err := c.Cache.Policy.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid CACHE_POLICY, available: %v: %w", eviction.Algorithms(), err)
+	}
+
+	if err := c.OpLog.Sync.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid OPLOG_SYNC: %w", err)
+	}
+
+	if c.OpLog.Path != "" && c.Snapshot.Path == "" {
+		return nil, errors.New("OPLOG_PATH requires SNAPSHOT_PATH, the log is compacted into the snapshot")
+	}
+
 	return &c, nil
 }
@@ -1,20 +1,31 @@
 package main
 
 import (
+	"context"
 	"github.com/fadyat/speedy/api"
 	"github.com/fadyat/speedy/eviction"
+	"github.com/fadyat/speedy/oplog"
 	"github.com/fadyat/speedy/server"
+	"github.com/fadyat/speedy/snapshot"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 var (
 	Version = "dev"
 )
 
+// shutdownTimeout limits the graceful stop, watch streams never end on
+// their own, so they are closed forcibly after it.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	initLogger()
 
@@ -29,10 +40,76 @@ func main() {
 				grpcStyleLogger(zap.L()),
 				logging.WithLogOnEvents(logging.StartCall, logging.FinishCall),
 			),
+			server.AdminInterceptor(c.Server.AdminToken),
+		),
+		grpc.ChainStreamInterceptor(
+			logging.StreamServerInterceptor(
+				grpcStyleLogger(zap.L()),
+				logging.WithLogOnEvents(logging.StartCall, logging.FinishCall),
+			),
 		),
 	)
 
-	cacheServer := server.NewCacheServer("", eviction.NewLRU(c.Cache.Capacity))
+	watches := server.NewWatchRegistry()
+	cache, err := eviction.NewAlgo(c.Cache.Policy, c.Cache.Capacity,
+		eviction.WithListener(watches.OnRemove),
+		eviction.WithListener(server.RemovalLogger(zap.L())),
+		eviction.WithMaxBytes(c.Cache.MaxBytes),
+	)
+	if err != nil {
+		zap.L().Fatal("failed to create cache", zap.Error(err))
+	}
+
+	if c.Snapshot.Path != "" {
+		loaded, e := snapshot.Load(c.Snapshot.Path, cache)
+		if e != nil {
+			zap.L().Fatal("failed to load snapshot", zap.String("path", c.Snapshot.Path), zap.Error(e))
+		}
+
+		zap.L().Info("snapshot loaded", zap.String("path", c.Snapshot.Path), zap.Int("keys", loaded))
+	}
+
+	var opLog *oplog.Log
+	if c.OpLog.Path != "" {
+		replayed, e := oplog.Replay(c.OpLog.Path, cache)
+		if e != nil {
+			zap.L().Fatal("failed to replay operation log", zap.String("path", c.OpLog.Path), zap.Error(e))
+		}
+
+		zap.L().Info("operation log replayed", zap.String("path", c.OpLog.Path), zap.Int("operations", replayed))
+		opLog, e = oplog.Open(c.OpLog.Path, c.Snapshot.Path, cache,
+			oplog.WithSyncPolicy(c.OpLog.Sync),
+			oplog.WithRewriteSize(c.OpLog.RewriteSize),
+		)
+		if e != nil {
+			zap.L().Fatal("failed to open operation log", zap.String("path", c.OpLog.Path), zap.Error(e))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go eviction.Sweep(ctx, cache, c.Cache.SweepPeriod)
+	switch {
+	case opLog != nil:
+		go opLog.Run(ctx)
+	case c.Snapshot.Path != "":
+		go snapshot.Run(ctx, c.Snapshot.Path, cache, c.Snapshot.Period)
+	}
+
+	go func() {
+		<-ctx.Done()
+		zap.L().Info("shutting down grpc server")
+
+		forced := time.AfterFunc(shutdownTimeout, s.Stop)
+		s.GracefulStop()
+		forced.Stop()
+	}()
+
+	serverOpts := []server.Option{server.WithWatchRegistry(watches)}
+	if opLog != nil {
+		serverOpts = append(serverOpts, server.WithOpLog(opLog))
+	}
+
+	cacheServer := server.NewCacheServer("", cache, serverOpts...)
 	api.RegisterCacheServiceServer(s, cacheServer)
 	reflection.Register(s)
 
@@ -43,6 +120,34 @@ func main() {
 
 	zap.L().Info("starting grpc server", zap.String("port", c.Server.GrpcPort), zap.String("version", Version))
 	if e := s.Serve(listener); e != nil {
+		stop()
 		zap.L().Fatal("failed to start grpc server", zap.Error(e))
 	}
+
+	stop()
+	if c.Snapshot.Path == "" {
+		return
+	}
+
+	saved, err := saveSnapshot(c.Snapshot.Path, cache, opLog)
+	if err != nil {
+		zap.L().Fatal("failed to save snapshot", zap.String("path", c.Snapshot.Path), zap.Error(err))
+	}
+
+	zap.L().Info("snapshot saved", zap.String("path", c.Snapshot.Path), zap.Int("keys", saved))
+}
+
+// saveSnapshot writes the final snapshot, with the operation log it's
+// written by the compaction, so the next start doesn't replay the log.
+func saveSnapshot(path string, cache eviction.Algorithm, opLog *oplog.Log) (int, error) {
+	if opLog == nil {
+		return snapshot.Save(path, cache)
+	}
+
+	saved, err := opLog.Compact()
+	if e := opLog.Close(); err == nil {
+		err = e
+	}
+
+	return saved, err
 }
@@ -0,0 +1,124 @@
+package eviction
+
+// arc is the adaptive replacement cache, it splits the cache between
+// the recently used keys, which were seen once, and the frequently used
+// keys, which were seen at least twice.
+//
+//   - t1 and t2 keep the cached keys, seen once and at least twice.
+//   - b1 and b2 are the ghost lists, they keep only the keys, recently
+//     evicted from t1 and t2.
+//   - p is the target size of t1, hit in b1 grows it and hit in b2 shrinks
+//     it, so the cache adapts to the current workload at runtime.
+type arc struct {
+	*store
+	t1, t2, b1, b2 list
+	ghosts         map[string]*Node
+	p              int
+
+	// revived is the ghost of the key, which is being added.
+	revived *Node
+}
+
+func NewARC(capacity int, opts ...Option) Algorithm {
+	a := &arc{
+		t1:     newList(),
+		t2:     newList(),
+		b1:     newList(),
+		b2:     newList(),
+		ghosts: make(map[string]*Node),
+	}
+
+	a.store = newStore(capacity, a, opts)
+	return a
+}
+
+func (a *arc) added(node *Node) {
+	a.revive(node.key)
+	if a.revived != nil {
+		a.t2.pushFront(node)
+	} else {
+		a.t1.pushFront(node)
+	}
+
+	a.revived = nil
+	a.trimGhosts()
+}
+
+// revive adapts the target size, if the key is in the ghost lists, and
+// forgets the ghost, so the key is added to t2.
+//
+// it's called before the eviction, to use the adapted target size, and
+// returns true, if the ghost was in b2.
+func (a *arc) revive(key string) bool {
+	ghost, ok := a.ghosts[key]
+	if !ok {
+		return false
+	}
+
+	inB2 := ghost.owner == &a.b2
+	if inB2 {
+		a.p = max(0, a.p-max(a.b1.len/a.b2.len, 1))
+	} else {
+		a.p = min(a.cap, a.p+max(a.b2.len/a.b1.len, 1))
+	}
+
+	a.forget(ghost)
+	a.revived = ghost
+	return inB2
+}
+
+// trimGhosts keeps t1 with b1 within the capacity and all lists within
+// the doubled capacity, dropping the oldest ghosts.
+func (a *arc) trimGhosts() {
+	for a.b1.len > 0 && a.t1.len+a.b1.len > a.cap {
+		a.forget(a.b1.back())
+	}
+
+	for a.b2.len > 0 && a.t1.len+a.t2.len+a.b1.len+a.b2.len > 2*a.cap {
+		a.forget(a.b2.back())
+	}
+}
+
+func (a *arc) forget(ghost *Node) {
+	ghost.owner.unlink(ghost)
+	delete(a.ghosts, ghost.key)
+}
+
+func (a *arc) accessed(node *Node) {
+	node.owner.unlink(node)
+	a.t2.pushFront(node)
+}
+
+func (a *arc) removed(node *Node) {
+	node.owner.unlink(node)
+}
+
+// victim evicts from t1, when it exceeds the target size, otherwise
+// from t2, the evicted key is remembered in the matching ghost list.
+func (a *arc) victim(key string) *Node {
+	inB2 := a.revive(key)
+
+	node, ghosts := a.t2.backExcept(key), &a.b2
+	if a.t1.len > 0 && (a.t1.len > a.p || (inB2 && a.t1.len == a.p) || node == nil) {
+		node, ghosts = a.t1.backExcept(key), &a.b1
+	}
+
+	ghost := &Node{key: node.key}
+	ghosts.pushFront(ghost)
+	a.ghosts[node.key] = ghost
+	return node
+}
+
+func (a *arc) reset() {
+	a.t1.reset()
+	a.t2.reset()
+	a.b1.reset()
+	a.b2.reset()
+	a.ghosts = make(map[string]*Node)
+	a.p, a.revived = 0, nil
+}
+
+func (a *arc) walk(fn func(node *Node)) {
+	a.t1.walkBack(fn)
+	a.t2.walkBack(fn)
+}
@@ -0,0 +1,327 @@
+package eviction
+
+import (
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listKeys returns the keys of the list from the most recently used one.
+func listKeys(l *list) []string {
+	keys := make([]string, 0, l.len)
+	for node := l.head.next; node != l.tail; node = node.next {
+		keys = append(keys, node.key)
+	}
+
+	return keys
+}
+
+type arcLists struct {
+	t1, t2, b1, b2 []string
+}
+
+func arcOrder(a *arc) arcLists {
+	return arcLists{
+		t1: listKeys(&a.t1),
+		t2: listKeys(&a.t2),
+		b1: listKeys(&a.b1),
+		b2: listKeys(&a.b2),
+	}
+}
+
+func TestArc_Get(t *testing.T) {
+	testCases := []struct {
+		name           string
+		cap            int
+		operate        func(t *testing.T, arc *arc)
+		verifyInternal func(t *testing.T, arc *arc)
+	}{
+		{
+			name: "get from empty arc",
+			cap:  10,
+			operate: func(t *testing.T, arc *arc) {
+				_, _, ok := arc.Get("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 0, arc.size)
+				require.Equal(t, arcLists{t1: []string{}, t2: []string{}, b1: []string{}, b2: []string{}}, arcOrder(arc))
+			},
+		},
+		{
+			name: "get moves key to frequent list",
+			cap:  10,
+			operate: func(t *testing.T, arc *arc) {
+				arc.Put("foo", []byte("bar"), 0)
+				arc.Put("bar", []byte("baz"), 0)
+				arc.Put("baz", []byte("qux"), 0)
+
+				val, _, ok := arc.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("bar"), val)
+				arc.Put("baz", []byte("quux"), 0)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 3, arc.size)
+				require.Equal(t, arcLists{
+					t1: []string{"bar"},
+					t2: []string{"baz", "foo"},
+					b1: []string{},
+					b2: []string{},
+				}, arcOrder(arc))
+			},
+		},
+		{
+			name: "evict from recent list without frequent keys",
+			cap:  2,
+			operate: func(t *testing.T, arc *arc) {
+				arc.Put("foo", []byte("bar"), 0)
+				arc.Put("bar", []byte("baz"), 0)
+				arc.Put("baz", []byte("qux"), 0)
+
+				_, _, ok := arc.Get("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 2, arc.size)
+				require.Equal(t, uint64(1), arc.evictions)
+				require.Equal(t, arcLists{
+					t1: []string{"baz", "bar"},
+					t2: []string{},
+					b1: []string{},
+					b2: []string{},
+				}, arcOrder(arc))
+			},
+		},
+		{
+			name: "ghost hits adapt target size",
+			cap:  2,
+			operate: func(t *testing.T, arc *arc) {
+				arc.Put("a", []byte("1"), 0)
+				arc.Get("a")
+				arc.Put("b", []byte("2"), 0)
+				arc.Put("c", []byte("3"), 0)
+				require.Equal(t, arcLists{
+					t1: []string{"c"},
+					t2: []string{"a"},
+					b1: []string{"b"},
+					b2: []string{},
+				}, arcOrder(arc))
+
+				arc.Put("b", []byte("2"), 0)
+				require.Equal(t, 1, arc.p)
+				require.Equal(t, arcLists{
+					t1: []string{"c"},
+					t2: []string{"b"},
+					b1: []string{},
+					b2: []string{"a"},
+				}, arcOrder(arc))
+
+				arc.Put("a", []byte("1"), 0)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 2, arc.size)
+				require.Equal(t, 0, arc.p)
+				require.Equal(t, uint64(3), arc.evictions)
+				require.Equal(t, arcLists{
+					t1: []string{},
+					t2: []string{"a", "b"},
+					b1: []string{"c"},
+					b2: []string{},
+				}, arcOrder(arc))
+			},
+		},
+		{
+			name: "ghost lists are bounded",
+			cap:  3,
+			operate: func(t *testing.T, arc *arc) {
+				for i := 0; i < 100; i++ {
+					key := strconv.Itoa(i)
+					arc.Put(key, []byte(key), 0)
+					if i%3 == 0 {
+						arc.Get(key)
+					}
+				}
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 3, arc.size)
+				require.Equal(t, arc.size, arc.t1.len+arc.t2.len)
+				require.LessOrEqual(t, arc.t1.len+arc.b1.len, arc.cap)
+				require.LessOrEqual(t, arc.t1.len+arc.t2.len+arc.b1.len+arc.b2.len, 2*arc.cap)
+				require.Equal(t, arc.b1.len+arc.b2.len, len(arc.ghosts))
+			},
+		},
+		{
+			name: "single capacity",
+			cap:  1,
+			operate: func(t *testing.T, arc *arc) {
+				arc.Put("foo", []byte("bar"), 0)
+				arc.Get("foo")
+				arc.Put("bar", []byte("baz"), 0)
+				arc.Put("baz", []byte("qux"), 0)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 1, arc.size)
+
+				val, _, ok := arc.Get("baz")
+				require.True(t, ok)
+				require.Equal(t, []byte("qux"), val)
+			},
+		},
+		{
+			name: "delete",
+			cap:  10,
+			operate: func(t *testing.T, arc *arc) {
+				arc.Put("foo", []byte("bar"), 0)
+				arc.Put("bar", []byte("baz"), 0)
+				arc.Get("bar")
+				require.True(t, arc.Delete("bar"))
+				require.False(t, arc.Delete("bar"))
+
+				_, _, ok := arc.Get("bar")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 1, arc.size)
+				require.Equal(t, uint64(6), arc.bytes)
+				require.Equal(t, arcLists{
+					t1: []string{"foo"},
+					t2: []string{},
+					b1: []string{},
+					b2: []string{},
+				}, arcOrder(arc))
+			},
+		},
+		{
+			name: "expired on get",
+			cap:  10,
+			operate: func(t *testing.T, arc *arc) {
+				now := time.Unix(0, 0)
+				arc.now = func() time.Time { return now }
+
+				arc.Put("foo", []byte("bar"), time.Second)
+				arc.Put("bar", []byte("baz"), 0)
+				arc.Get("foo")
+
+				now = now.Add(time.Second)
+				_, _, ok := arc.Get("foo")
+				require.False(t, ok)
+				require.Equal(t, 0, arc.DeleteExpired())
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 1, arc.size)
+				require.Equal(t, uint64(1), arc.expired)
+				require.Empty(t, arc.ghosts)
+			},
+		},
+		{
+			name: "compare and swap and incr",
+			cap:  10,
+			operate: func(t *testing.T, arc *arc) {
+				version, ok := arc.CompareAndSwap("foo", 0, []byte("bar"), 0)
+				require.True(t, ok)
+				_, ok = arc.CompareAndSwap("foo", 0, []byte("baz"), 0)
+				require.False(t, ok)
+
+				val, _, err := arc.Incr("counter", 2, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(2), val)
+				val, _, err = arc.Incr("counter", -5, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(-3), val)
+
+				_, ok = arc.CompareAndSwap("foo", version, []byte("baz"), 0)
+				require.True(t, ok)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 2, arc.size)
+				require.Equal(t, []string{"foo", "counter"}, listKeys(&arc.t2))
+			},
+		},
+		{
+			name: "listener notified about removals",
+			cap:  2,
+			operate: func(t *testing.T, arc *arc) {
+				var removed []removal
+				arc.notifier.listeners = append(arc.notifier.listeners, func(key string, val []byte, reason Reason) {
+					removed = append(removed, removal{key: key, val: val, reason: reason})
+				})
+
+				arc.Put("foo", []byte("bar"), 0)
+				arc.Get("foo")
+				arc.Put("bar", []byte("baz"), 0)
+				arc.Put("baz", []byte("qux"), 0)
+
+				require.Equal(t, []removal{
+					{key: "bar", val: []byte("baz"), reason: ReasonCapacity},
+				}, removed)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 2, arc.size)
+				require.Empty(t, arc.notifier.pending)
+			},
+		},
+		{
+			name: "flush all",
+			cap:  2,
+			operate: func(t *testing.T, arc *arc) {
+				arc.Put("a", []byte("1"), time.Hour)
+				arc.Get("a")
+				arc.Put("b", []byte("2"), 0)
+				arc.Put("c", []byte("3"), 0)
+				arc.Put("b", []byte("2"), 0)
+				require.Equal(t, 2, arc.Flush(""))
+
+				arc.Put("foo", []byte("bar"), 0)
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, 1, arc.size)
+				require.Equal(t, 0, arc.p)
+				require.Equal(t, 0, arc.expiry.Len())
+				require.Empty(t, arc.ghosts)
+				require.Equal(t, arcLists{
+					t1: []string{"foo"},
+					t2: []string{},
+					b1: []string{},
+					b2: []string{},
+				}, arcOrder(arc))
+			},
+		},
+		{
+			name: "in goroutines",
+			cap:  100,
+			operate: func(t *testing.T, arc *arc) {
+				var wg sync.WaitGroup
+				for i := 0; i < arc.cap*3; i++ {
+					wg.Add(1)
+
+					go func(i int) {
+						defer wg.Done()
+
+						key := strconv.Itoa(i % (arc.cap * 2))
+						arc.Put(key, []byte(key), 0)
+						arc.Get(key)
+					}(i)
+				}
+
+				wg.Wait()
+			},
+			verifyInternal: func(t *testing.T, arc *arc) {
+				require.Equal(t, arc.cap, arc.size)
+				require.Equal(t, arc.size, arc.t1.len+arc.t2.len)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			algo := NewARC(tc.cap)
+
+			ep, _ := algo.(*arc)
+			tc.operate(t, ep)
+			tc.verifyInternal(t, ep)
+		})
+	}
+}
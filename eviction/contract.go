@@ -1,24 +1,160 @@
 package eviction
 
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNotInteger = errors.New("value is not an integer")
+	ErrOverflow   = errors.New("increment or decrement would overflow")
+)
+
 type Node struct {
-	key, val   string
+	key        string
+	val        []byte
 	prev, next *Node
+
+	// version is taken from the cache-wide counter on every write, so
+	// it's never reused, even if the key is deleted and created again.
+	version uint64
+
+	// expiresAt is the deadline after which the node is treated as missing,
+	// zero value means that the node never expires.
+	expiresAt time.Time
+	heapIdx   int
+
+	// owner is the list, which holds the node, policies use it to find
+	// the segment of the node.
+	owner *list
+
+	// bucket is the frequency bucket of the node, used only by lfu.
+	bucket *bucket
+
+	// accessedAt is the unix nano time of the last access, it's updated
+	// atomically by readers, used only by sampled.
+	accessedAt atomic.Int64
+	sampleIdx  int
+}
+
+func (n *Node) expired(now time.Time) bool {
+	return !n.expiresAt.IsZero() && !now.Before(n.expiresAt)
+}
+
+// Entry is a copy of the key-value pair, returned by Scan.
+type Entry struct {
+	Key     string
+	Value   []byte
+	Version uint64
+}
+
+// Record is a copy of the entry with its deadline, returned by Snapshot,
+// zero ExpiresAt means that the key never expires.
+type Record struct {
+	Key       string
+	Value     []byte
+	Version   uint64
+	ExpiresAt time.Time
+}
+
+// Stats describes the current state of the cache and the keys, which
+// were removed by the cache itself.
+type Stats struct {
+	Items uint32
+
+	// Bytes is the size of stored keys and values, Memory adds the
+	// overhead of every key to it and is limited by MaxBytes.
+	Bytes    uint64
+	Memory   uint64
+	MaxBytes uint64
+
+	Capacity    uint64
+	Evictions   uint64
+	Expirations uint64
 }
 
 type Algorithm interface {
 
-	// Get returns the value associated with the given key.
-	// - If the key exists, it returns the value and true, otherwise it returns
-	//   nil and false.
-	Get(key string) (string, bool)
+	// Get returns the value associated with the given key and its version.
+	// - If the key exists, it returns the value, version and true, otherwise
+	//   it returns nil, zero and false.
+	// - Expired keys are treated as missing ones.
+	Get(key string) ([]byte, uint64, bool)
 
 	// Put inserts the given key-value pair into the cache.
 	// - If the key already exists, it updates the value.
 	// - If the key does not exist, it inserts the key-value pair.
-	// - If the cache is full, it evicts the least recently used item before
-	//   inserting the new key-value pair.
-	Put(key, val string)
+	// - If the cache is full, it evicts the item, chosen by the eviction
+	//   policy, after inserting the new key-value pair.
+	// - If the ttl is positive, the key expires after it, otherwise the key
+	//   lives until it's evicted.
+	// - It returns the new version of the key.
+	Put(key string, val []byte, ttl time.Duration) uint64
+
+	// CompareAndSwap puts the key-value pair only if the current version of
+	// the key is equal to the expected one, missing keys have zero version.
+	// - On success, it returns the new version and true.
+	// - On conflict, it returns the current version and false.
+	CompareAndSwap(key string, expected uint64, val []byte, ttl time.Duration) (uint64, bool)
+
+	// Incr atomically adds delta to the value, stored as a decimal int64,
+	// and returns the result with the new version of the key.
+	// - If the key does not exist, it's created with the delta value and
+	//   the given ttl, existing keys keep their deadlines.
+	// - If the value isn't an integer, ErrNotInteger is returned.
+	// - If the result overflows int64, ErrOverflow is returned.
+	Incr(key string, delta int64, ttl time.Duration) (int64, uint64, error)
+
+	// Peek returns the record of the key with its deadline, it doesn't
+	// affect the eviction order, expired keys are treated as missing.
+	Peek(key string) (Record, bool)
+
+	// Delete removes the given key from the cache.
+	// - If the key exists, it removes the key-value pair and returns true,
+	//   otherwise it returns false.
+	Delete(key string) bool
+
+	// DeleteExpired removes all expired keys from the cache and returns
+	// the number of removed keys.
+	DeleteExpired() int
+
+	// Flush removes all keys with the prefix, empty prefix removes all keys,
+	// and returns the number of removed keys.
+	// - Versions are not reset, so CompareAndSwap with the version read
+	//   before the flush never succeeds.
+	Flush(prefix string) int
+
+	// Scan returns up to limit entries with the prefix, which keys are
+	// greater than the cursor, in the lexicographical order.
+	// - Next cursor is the last returned key, or empty, when there are no
+	//   more entries.
+	// - Scan doesn't affect the eviction order, expired keys are skipped.
+	Scan(prefix, cursor string, limit int) ([]Entry, string)
 
 	// Len returns the number of items in the cache, included only active ones.
 	Len() uint32
+
+	// Stats returns the current stats of the cache, expired keys are
+	// removed before counting.
+	Stats() Stats
+
+	// Snapshot returns all active entries from the coldest to the hottest
+	// one, according to the policy, so restoring them in the same order
+	// keeps the eviction order.
+	Snapshot() []Record
+
+	// Restore puts the records in the given order, keeping their versions
+	// and deadlines, expired records are skipped.
+	// - Versions of the new writes are greater than the restored ones.
+	Restore(records []Record)
+
+	// Version returns the cache-wide version counter, versions of the new
+	// writes are greater than it, including versions of the deleted keys.
+	Version() uint64
+
+	// AdvanceVersion moves the version counter forward to the given one,
+	// it's never moved back, so the versions, issued before the restart,
+	// are never reused after the restore.
+	AdvanceVersion(version uint64)
 }
@@ -0,0 +1,79 @@
+package eviction
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expirations is a min-heap of nodes ordered by their deadlines, used to
+// find expired nodes without scanning the whole cache.
+//
+// only nodes with a non-zero deadline are tracked.
+type expirations []*Node
+
+func (e expirations) Len() int { return len(e) }
+
+func (e expirations) Less(i, j int) bool {
+	return e[i].expiresAt.Before(e[j].expiresAt)
+}
+
+func (e expirations) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+	e[i].heapIdx, e[j].heapIdx = i, j
+}
+
+func (e *expirations) Push(x any) {
+	node, _ := x.(*Node)
+	node.heapIdx = len(*e)
+	*e = append(*e, node)
+}
+
+func (e *expirations) Pop() any {
+	old := *e
+	node := old[len(old)-1]
+	old[len(old)-1] = nil
+	*e = old[:len(old)-1]
+	return node
+}
+
+// setDeadline updates the node deadline, keeping the heap consistent.
+// - zero ttl removes the deadline, so the node lives until it's evicted.
+func (e *expirations) setDeadline(node *Node, ttl time.Duration, now time.Time) {
+	if ttl <= 0 {
+		e.setExpiresAt(node, time.Time{})
+		return
+	}
+
+	e.setExpiresAt(node, now.Add(ttl))
+}
+
+// setExpiresAt tracks the node with the exact deadline, zero deadline
+// means that the node never expires.
+func (e *expirations) setExpiresAt(node *Node, at time.Time) {
+	e.untrack(node)
+	if at.IsZero() {
+		return
+	}
+
+	node.expiresAt = at
+	heap.Push(e, node)
+}
+
+func (e *expirations) untrack(node *Node) {
+	if node.expiresAt.IsZero() {
+		return
+	}
+
+	heap.Remove(e, node.heapIdx)
+	node.expiresAt = time.Time{}
+}
+
+// nextExpired returns the node with the closest deadline, if it's already
+// passed, otherwise nil.
+func (e expirations) nextExpired(now time.Time) *Node {
+	if len(e) == 0 || !e[0].expired(now) {
+		return nil
+	}
+
+	return e[0]
+}
@@ -0,0 +1,98 @@
+package eviction
+
+// bucket keeps the nodes with the same access frequency, ordered by
+// recency, the front is the most recently used node.
+type bucket struct {
+	freq       uint64
+	items      list
+	prev, next *bucket
+}
+
+// lfu evicts the least frequently used key, ties are broken by recency,
+// so the least recently used key of the least frequent bucket is evicted.
+//
+// buckets are kept in the list, sorted by frequency, so every operation
+// takes O(1): the node moves only to the neighbour bucket.
+type lfu struct {
+	*store
+
+	// buckets is the sentinel of the circular list of buckets, buckets.next
+	// is the least frequent bucket.
+	buckets *bucket
+}
+
+func NewLFU(capacity int, opts ...Option) Algorithm {
+	l := &lfu{buckets: &bucket{}}
+	l.buckets.prev, l.buckets.next = l.buckets, l.buckets
+	l.store = newStore(capacity, l, opts)
+	return l
+}
+
+// insertAfter creates the empty bucket with the given frequency right
+// after the given one.
+func (l *lfu) insertAfter(b *bucket, freq uint64) *bucket {
+	nb := &bucket{freq: freq, items: newList(), prev: b, next: b.next}
+	b.next.prev = nb
+	b.next = nb
+	return nb
+}
+
+// unlinkEmpty removes the bucket from the list, if it has no nodes.
+func (l *lfu) unlinkEmpty(b *bucket) {
+	if b.items.len != 0 {
+		return
+	}
+
+	b.prev.next, b.next.prev = b.next, b.prev
+	b.prev, b.next = nil, nil
+}
+
+func (l *lfu) added(node *Node) {
+	first := l.buckets.next
+	if first == l.buckets || first.freq != 1 {
+		first = l.insertAfter(l.buckets, 1)
+	}
+
+	first.items.pushFront(node)
+	node.bucket = first
+}
+
+func (l *lfu) accessed(node *Node) {
+	current := node.bucket
+	next := current.next
+	if next == l.buckets || next.freq != current.freq+1 {
+		next = l.insertAfter(current, current.freq+1)
+	}
+
+	current.items.unlink(node)
+	next.items.pushFront(node)
+	node.bucket = next
+	l.unlinkEmpty(current)
+}
+
+func (l *lfu) removed(node *Node) {
+	current := node.bucket
+	current.items.unlink(node)
+	node.bucket = nil
+	l.unlinkEmpty(current)
+}
+
+func (l *lfu) victim(key string) *Node {
+	for b := l.buckets.next; b != l.buckets; b = b.next {
+		if node := b.items.backExcept(key); node != nil {
+			return node
+		}
+	}
+
+	return nil
+}
+
+func (l *lfu) reset() {
+	l.buckets.prev, l.buckets.next = l.buckets, l.buckets
+}
+
+func (l *lfu) walk(fn func(node *Node)) {
+	for b := l.buckets.next; b != l.buckets; b = b.next {
+		b.items.walkBack(fn)
+	}
+}
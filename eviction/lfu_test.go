@@ -0,0 +1,355 @@
+package eviction
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type freqOrder struct {
+	freq uint64
+	keys []string
+}
+
+// bucketsOrder returns the buckets from the least frequent one, with
+// keys from the most recently used one.
+func bucketsOrder(l *lfu) []freqOrder {
+	order := make([]freqOrder, 0)
+	for b := l.buckets.next; b != l.buckets; b = b.next {
+		order = append(order, freqOrder{freq: b.freq, keys: listKeys(&b.items)})
+	}
+
+	return order
+}
+
+func TestLfu_Get(t *testing.T) {
+	testCases := []struct {
+		name           string
+		cap            int
+		operate        func(t *testing.T, lfu *lfu)
+		verifyInternal func(t *testing.T, lfu *lfu)
+	}{
+		{
+			name: "get from empty lfu",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				_, _, ok := lfu.Get("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 0, lfu.size)
+				require.Empty(t, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "get increments frequency",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("foo", []byte("bar"), 0)
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Put("baz", []byte("qux"), 0)
+
+				val, _, ok := lfu.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("bar"), val)
+				lfu.Get("foo")
+				lfu.Get("baz")
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 3, lfu.size)
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"bar"}},
+					{freq: 2, keys: []string{"baz"}},
+					{freq: 3, keys: []string{"foo"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "putting the same key twice",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("foo", []byte("bar"), 0)
+				lfu.Put("foo", []byte("baz"), 0)
+				lfu.Put("bar", []byte("baz"), 0)
+
+				val, _, ok := lfu.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("baz"), val)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 2, lfu.size)
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"bar"}},
+					{freq: 3, keys: []string{"foo"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "evict least frequent",
+			cap:  3,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("foo", []byte("bar"), 0)
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Put("baz", []byte("qux"), 0)
+				lfu.Get("foo")
+				lfu.Get("bar")
+				lfu.Put("qux", []byte("quux"), 0)
+
+				_, _, ok := lfu.Get("baz")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 3, lfu.size)
+				require.Equal(t, uint64(1), lfu.evictions)
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"qux"}},
+					{freq: 2, keys: []string{"bar", "foo"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "ties are broken by recency",
+			cap:  3,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("foo", []byte("bar"), 0)
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Put("baz", []byte("qux"), 0)
+				lfu.Get("bar")
+				lfu.Get("foo")
+				lfu.Get("baz")
+				lfu.Put("qux", []byte("quux"), 0)
+				lfu.Put("quux", []byte("corge"), 0)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 3, lfu.size)
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"quux"}},
+					{freq: 2, keys: []string{"baz", "foo"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "single capacity",
+			cap:  1,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("foo", []byte("bar"), 0)
+				lfu.Get("foo")
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Put("baz", []byte("qux"), 0)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 1, lfu.size)
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"baz"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "delete",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("foo", []byte("bar"), 0)
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Get("bar")
+				require.True(t, lfu.Delete("bar"))
+				require.False(t, lfu.Delete("bar"))
+
+				_, _, ok := lfu.Get("bar")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 1, lfu.size)
+				require.Equal(t, uint64(6), lfu.bytes)
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"foo"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "expired on get",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				now := time.Unix(0, 0)
+				lfu.now = func() time.Time { return now }
+
+				lfu.Put("foo", []byte("bar"), time.Second)
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Get("foo")
+
+				now = now.Add(time.Second)
+				_, _, ok := lfu.Get("foo")
+				require.False(t, ok)
+				require.Equal(t, 0, lfu.DeleteExpired())
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 1, lfu.size)
+				require.Equal(t, uint64(1), lfu.expired)
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"bar"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "compare and swap and incr",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				version, ok := lfu.CompareAndSwap("foo", 0, []byte("bar"), 0)
+				require.True(t, ok)
+				_, ok = lfu.CompareAndSwap("foo", 0, []byte("baz"), 0)
+				require.False(t, ok)
+
+				val, _, err := lfu.Incr("counter", 2, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(2), val)
+				val, _, err = lfu.Incr("counter", -5, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(-3), val)
+
+				_, _, err = lfu.Incr("foo", 1, 0)
+				require.ErrorIs(t, err, ErrNotInteger)
+				_, ok = lfu.CompareAndSwap("foo", version, []byte("baz"), 0)
+				require.True(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 2, lfu.size)
+				require.Equal(t, []freqOrder{
+					{freq: 2, keys: []string{"foo", "counter"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "listener notified about removals",
+			cap:  2,
+			operate: func(t *testing.T, lfu *lfu) {
+				now := time.Unix(0, 0)
+				lfu.now = func() time.Time { return now }
+
+				var removed []removal
+				lfu.notifier.listeners = append(lfu.notifier.listeners, func(key string, val []byte, reason Reason) {
+					removed = append(removed, removal{key: key, val: val, reason: reason})
+				})
+
+				lfu.Put("foo", []byte("bar"), time.Second)
+				lfu.Get("foo")
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Put("baz", []byte("qux"), 0)
+
+				now = now.Add(time.Second)
+				require.Equal(t, 1, lfu.DeleteExpired())
+
+				require.Equal(t, []removal{
+					{key: "bar", val: []byte("baz"), reason: ReasonCapacity},
+					{key: "foo", val: []byte("bar"), reason: ReasonExpired},
+				}, removed)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 1, lfu.size)
+				require.Empty(t, lfu.notifier.pending)
+			},
+		},
+		{
+			name: "growing update doesn't evict the key",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.maxBytes = 2*entryOverhead + 100
+
+				lfu.Put("a", []byte("x"), 0)
+				lfu.Put("b", []byte("y"), 0)
+				for i := 0; i < 5; i++ {
+					lfu.Get("b")
+				}
+
+				require.Equal(t, uint64(3), lfu.Put("a", bytes.Repeat([]byte("x"), 150), 0))
+
+				val, _, ok := lfu.Get("a")
+				require.True(t, ok)
+				require.Len(t, val, 150)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 1, lfu.size)
+				require.Equal(t, uint64(1), lfu.evictions)
+				require.Equal(t, []freqOrder{
+					{freq: 3, keys: []string{"a"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "stats",
+			cap:  2,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("foo", []byte("bar"), 0)
+				lfu.Put("bar", []byte("baz"), 0)
+				lfu.Get("foo")
+				lfu.Put("baz", []byte("quux"), 0)
+				require.Equal(t, Stats{Items: 2, Bytes: 13, Memory: 269, Capacity: 2, Evictions: 1}, lfu.Stats())
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 2, lfu.size)
+			},
+		},
+		{
+			name: "flush",
+			cap:  10,
+			operate: func(t *testing.T, lfu *lfu) {
+				lfu.Put("user:1", []byte("a"), time.Hour)
+				lfu.Put("order:1", []byte("x"), 0)
+				lfu.Put("user:2", []byte("b"), 0)
+				lfu.Get("user:2")
+				require.Equal(t, 2, lfu.Flush("user:"))
+				require.Equal(t, 1, lfu.Flush(""))
+
+				lfu.Put("foo", []byte("bar"), 0)
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, 1, lfu.size)
+				require.Equal(t, 0, lfu.expiry.Len())
+				require.Equal(t, []freqOrder{
+					{freq: 1, keys: []string{"foo"}},
+				}, bucketsOrder(lfu))
+			},
+		},
+		{
+			name: "in goroutines",
+			cap:  100,
+			operate: func(t *testing.T, lfu *lfu) {
+				var wg sync.WaitGroup
+				for i := 0; i < lfu.cap*2; i++ {
+					wg.Add(1)
+
+					go func(i int) {
+						defer wg.Done()
+
+						key := strconv.Itoa(i % lfu.cap)
+						lfu.Put(key, []byte(key), 0)
+						lfu.Get(key)
+					}(i)
+				}
+
+				wg.Wait()
+			},
+			verifyInternal: func(t *testing.T, lfu *lfu) {
+				require.Equal(t, lfu.cap, lfu.size)
+
+				var total int
+				for _, b := range bucketsOrder(lfu) {
+					total += len(b.keys)
+				}
+
+				require.Equal(t, lfu.cap, total)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			algo := NewLFU(tc.cap)
+
+			ep, _ := algo.(*lfu)
+			tc.operate(t, ep)
+			tc.verifyInternal(t, ep)
+		})
+	}
+}
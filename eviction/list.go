@@ -0,0 +1,78 @@
+package eviction
+
+// list is the doubly linked list of nodes with sentinel head and tail,
+// the front is the most recently used node.
+//
+// node can be stored only in one list at a time.
+type list struct {
+	head, tail *Node
+	len        int
+}
+
+func newList() list {
+	head, tail := &Node{}, &Node{}
+	head.next, tail.prev = tail, head
+
+	return list{head: head, tail: tail}
+}
+
+func (l *list) pushFront(node *Node) {
+	node.next = l.head.next
+	l.head.next.prev = node
+	node.prev = l.head
+	l.head.next = node
+	node.owner = l
+	l.len++
+}
+
+func (l *list) unlink(node *Node) {
+	left, right := node.prev, node.next
+	if left != nil {
+		left.next = right
+	}
+
+	if right != nil {
+		right.prev = left
+	}
+
+	node.prev, node.next, node.owner = nil, nil, nil
+	l.len--
+}
+
+func (l *list) moveToFront(node *Node) {
+	l.unlink(node)
+	l.pushFront(node)
+}
+
+// back returns the least recently used node, or nil, if the list is empty.
+func (l *list) back() *Node {
+	if l.len == 0 {
+		return nil
+	}
+
+	return l.tail.prev
+}
+
+// backExcept returns the least recently used node, which key isn't the
+// given one, or nil, if there is no such node.
+func (l *list) backExcept(key string) *Node {
+	for node := l.tail.prev; node != l.head; node = node.prev {
+		if node.key != key {
+			return node
+		}
+	}
+
+	return nil
+}
+
+func (l *list) reset() {
+	l.head.next, l.tail.prev = l.tail, l.head
+	l.len = 0
+}
+
+// walkBack calls fn for every node from the least recently used one.
+func (l *list) walkBack(fn func(node *Node)) {
+	for node := l.tail.prev; node != l.head; node = node.prev {
+		fn(node)
+	}
+}
@@ -0,0 +1,92 @@
+package eviction
+
+// Reason describes why the key was removed or its value was dropped.
+type Reason int
+
+const (
+	// ReasonCapacity the key was evicted to free space for another one.
+	ReasonCapacity Reason = iota
+
+	// ReasonExpired the key outlived its ttl.
+	ReasonExpired
+
+	// ReasonDeleted the key was deleted by the caller.
+	ReasonDeleted
+
+	// ReasonReplaced the value was overwritten, the old value is passed
+	// to the listener.
+	ReasonReplaced
+
+	// ReasonFlushed the key was removed by Flush.
+	ReasonFlushed
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonFlushed:
+		return "flushed"
+	default:
+		return "unknown"
+	}
+}
+
+// Listener is notified about every removed key and every replaced value,
+// it's called outside the cache lock, so it may call the cache back.
+type Listener func(key string, val []byte, reason Reason)
+
+// WithListener registers the listener, listeners are called in the order
+// they were registered.
+func WithListener(l Listener) Option {
+	return func(o *options) {
+		o.listeners = append(o.listeners, l)
+	}
+}
+
+// removal is the notification, which is collected under the cache lock
+// and delivered to the listeners after the lock is released.
+type removal struct {
+	key    string
+	val    []byte
+	reason Reason
+}
+
+type notifier struct {
+	listeners []Listener
+	pending   []removal
+}
+
+func (n *notifier) collect(key string, val []byte, reason Reason) {
+	if len(n.listeners) == 0 {
+		return
+	}
+
+	n.pending = append(n.pending, removal{key: key, val: val, reason: reason})
+}
+
+// flush takes the collected notifications, it must be called under the
+// cache lock, returned func delivers them and must be called after the
+// lock is released.
+func (n *notifier) flush() func() {
+	if len(n.pending) == 0 {
+		return func() {}
+	}
+
+	pending := n.pending
+	n.pending = nil
+
+	return func() {
+		for _, r := range pending {
+			for _, l := range n.listeners {
+				l(r.key, r.val, r.reason)
+			}
+		}
+	}
+}
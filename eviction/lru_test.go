@@ -1,16 +1,19 @@
 package eviction
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/require"
+	"math"
 	"strconv"
 	"sync"
 	"testing"
+	"time"
 )
 
 func isValidOrder(order []Node, head *Node) bool {
 	current := head.next
-	for _, node := range order {
-		if current.key != node.key || current.val != node.val {
+	for i := range order {
+		if current.key != order[i].key || !bytes.Equal(current.val, order[i].val) {
 			return false
 		}
 
@@ -31,7 +34,7 @@ func TestLru_Get(t *testing.T) {
 			name: "get from empty lru",
 			cap:  10,
 			operate: func(t *testing.T, lru *lru) {
-				_, ok := lru.Get("foo")
+				_, _, ok := lru.Get("foo")
 				require.False(t, ok)
 			},
 			verifyInternal: func(t *testing.T, lru *lru) {
@@ -43,13 +46,13 @@ func TestLru_Get(t *testing.T) {
 			name: "get from non-empty lru",
 			cap:  10,
 			operate: func(t *testing.T, lru *lru) {
-				lru.Put("foo", "bar")
-				_, ok := lru.Get("foo")
+				lru.Put("foo", []byte("bar"), 0)
+				_, _, ok := lru.Get("foo")
 				require.True(t, ok)
 			},
 			verifyInternal: func(t *testing.T, lru *lru) {
 				order := []Node{
-					{key: "foo", val: "bar"},
+					{key: "foo", val: []byte("bar")},
 				}
 
 				require.Equal(t, 1, lru.size)
@@ -60,14 +63,14 @@ func TestLru_Get(t *testing.T) {
 			name: "putting the same key twice",
 			cap:  10,
 			operate: func(t *testing.T, lru *lru) {
-				lru.Put("foo", "bar")
-				lru.Put("foo", "baz")
-				lru.Put("bar", "baz")
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("foo", []byte("baz"), 0)
+				lru.Put("bar", []byte("baz"), 0)
 			},
 			verifyInternal: func(t *testing.T, lru *lru) {
 				order := []Node{
-					{key: "bar", val: "baz"},
-					{key: "foo", val: "baz"},
+					{key: "bar", val: []byte("baz")},
+					{key: "foo", val: []byte("baz")},
 				}
 
 				require.Equal(t, 2, lru.size)
@@ -78,16 +81,16 @@ func TestLru_Get(t *testing.T) {
 			name: "get and promote",
 			cap:  10,
 			operate: func(t *testing.T, lru *lru) {
-				lru.Put("foo", "bar")
-				lru.Put("bar", "baz")
-				lru.Put("baz", "qux")
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("baz", []byte("qux"), 0)
 				lru.Get("foo")
 			},
 			verifyInternal: func(t *testing.T, lru *lru) {
 				order := []Node{
-					{key: "foo", val: "bar"},
-					{key: "baz", val: "qux"},
-					{key: "bar", val: "baz"},
+					{key: "foo", val: []byte("bar")},
+					{key: "baz", val: []byte("qux")},
+					{key: "bar", val: []byte("baz")},
 				}
 
 				require.Equal(t, 3, lru.size)
@@ -98,39 +101,704 @@ func TestLru_Get(t *testing.T) {
 			name: "evict",
 			cap:  3,
 			operate: func(t *testing.T, lru *lru) {
-				lru.Put("foo", "bar")
-				lru.Put("bar", "baz")
-				lru.Put("baz", "qux")
-				lru.Put("qux", "quux")
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("baz", []byte("qux"), 0)
+				lru.Put("qux", []byte("quux"), 0)
 			},
 			verifyInternal: func(t *testing.T, lru *lru) {
 				order := []Node{
-					{key: "qux", val: "quux"},
-					{key: "baz", val: "qux"},
-					{key: "bar", val: "baz"},
+					{key: "qux", val: []byte("quux")},
+					{key: "baz", val: []byte("qux")},
+					{key: "bar", val: []byte("baz")},
 				}
 
 				require.Equal(t, 3, lru.size)
 				require.True(t, isValidOrder(order, lru.head))
 			},
 		},
+		{
+			name: "delete",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("baz", []byte("qux"), 0)
+				require.True(t, lru.Delete("bar"))
+
+				_, _, ok := lru.Get("bar")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "baz", val: []byte("qux")},
+					{key: "foo", val: []byte("bar")},
+				}
+
+				require.Equal(t, 2, lru.size)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "delete missing key",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				lru.Put("foo", []byte("bar"), 0)
+				require.False(t, lru.Delete("bar"))
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "foo", val: []byte("bar")},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "expired on get",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("foo", []byte("bar"), time.Second)
+				lru.Put("bar", []byte("baz"), 0)
+
+				_, _, ok := lru.Get("foo")
+				require.True(t, ok)
+
+				now = now.Add(time.Second)
+				_, _, ok = lru.Get("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "bar", val: []byte("baz")},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.Equal(t, 0, lru.expiry.Len())
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "delete expired key",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("foo", []byte("bar"), time.Second)
+				lru.Put("bar", []byte("baz"), 0)
+
+				now = now.Add(time.Second)
+				require.False(t, lru.Delete("foo"))
+				require.Equal(t, uint64(1), lru.expired)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "bar", val: []byte("baz")},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.Equal(t, 0, lru.expiry.Len())
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "len counts only live keys",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("foo", []byte("bar"), time.Second)
+				lru.Put("bar", []byte("baz"), 2*time.Second)
+				lru.Put("baz", []byte("qux"), 0)
+				require.Equal(t, uint32(3), lru.Len())
+
+				now = now.Add(time.Second)
+				require.Equal(t, uint32(2), lru.Len())
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "baz", val: []byte("qux")},
+					{key: "bar", val: []byte("baz")},
+				}
+
+				require.Equal(t, 2, lru.size)
+				require.Equal(t, 1, lru.expiry.Len())
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "delete expired",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("foo", []byte("bar"), 3*time.Second)
+				lru.Put("bar", []byte("baz"), time.Second)
+				lru.Put("baz", []byte("qux"), 2*time.Second)
+				lru.Put("qux", []byte("quux"), 0)
+
+				now = now.Add(2 * time.Second)
+				require.Equal(t, 2, lru.DeleteExpired())
+				require.Equal(t, 0, lru.DeleteExpired())
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "qux", val: []byte("quux")},
+					{key: "foo", val: []byte("bar")},
+				}
+
+				require.Equal(t, 2, lru.size)
+				require.Equal(t, 1, lru.expiry.Len())
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "overwrite removes ttl",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("foo", []byte("bar"), time.Second)
+				lru.Put("foo", []byte("baz"), 0)
+
+				now = now.Add(time.Hour)
+				val, _, ok := lru.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("baz"), val)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				require.Equal(t, 1, lru.size)
+				require.Equal(t, 0, lru.expiry.Len())
+			},
+		},
+		{
+			name: "evict expiring key",
+			cap:  2,
+			operate: func(t *testing.T, lru *lru) {
+				lru.Put("foo", []byte("bar"), time.Hour)
+				lru.Put("bar", []byte("baz"), time.Hour)
+				lru.Put("baz", []byte("qux"), 0)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "baz", val: []byte("qux")},
+					{key: "bar", val: []byte("baz")},
+				}
+
+				require.Equal(t, 2, lru.size)
+				require.Equal(t, 1, lru.expiry.Len())
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "versions",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				v1 := lru.Put("foo", []byte("bar"), 0)
+				v2 := lru.Put("bar", []byte("baz"), 0)
+				v3 := lru.Put("foo", []byte("baz"), 0)
+				require.Less(t, v1, v2)
+				require.Less(t, v2, v3)
+
+				_, version, ok := lru.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, v3, version)
+
+				require.True(t, lru.Delete("foo"))
+				v4 := lru.Put("foo", []byte("qux"), 0)
+				require.Less(t, v3, v4)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				require.Equal(t, uint64(4), lru.version)
+			},
+		},
+		{
+			name: "compare and swap",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				version, ok := lru.CompareAndSwap("foo", 0, []byte("bar"), 0)
+				require.True(t, ok)
+
+				current, ok := lru.CompareAndSwap("foo", 0, []byte("baz"), 0)
+				require.False(t, ok)
+				require.Equal(t, version, current)
+
+				_, ok = lru.CompareAndSwap("foo", version, []byte("baz"), 0)
+				require.True(t, ok)
+
+				_, ok = lru.CompareAndSwap("foo", version, []byte("qux"), 0)
+				require.False(t, ok)
+
+				_, ok = lru.CompareAndSwap("bar", version, []byte("qux"), 0)
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "foo", val: []byte("baz")},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "compare and swap expired key",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				version := lru.Put("foo", []byte("bar"), time.Second)
+				now = now.Add(time.Second)
+
+				_, ok := lru.CompareAndSwap("foo", version, []byte("baz"), 0)
+				require.False(t, ok)
+
+				_, ok = lru.CompareAndSwap("foo", 0, []byte("baz"), 0)
+				require.True(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				require.Equal(t, 1, lru.size)
+				require.Equal(t, 0, lru.expiry.Len())
+			},
+		},
+		{
+			name: "incr",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				val, _, err := lru.Incr("foo", 5, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(5), val)
+
+				val, _, err = lru.Incr("foo", -7, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(-2), val)
+
+				lru.Put("bar", []byte("baz"), 0)
+				_, _, err = lru.Incr("bar", 1, 0)
+				require.Equal(t, ErrNotInteger, err)
+
+				lru.Put("baz", []byte(strconv.FormatInt(math.MaxInt64, 10)), 0)
+				_, _, err = lru.Incr("baz", 1, 0)
+				require.Equal(t, ErrOverflow, err)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "baz", val: []byte(strconv.FormatInt(math.MaxInt64, 10))},
+					{key: "bar", val: []byte("baz")},
+					{key: "foo", val: []byte("-2")},
+				}
+
+				require.Equal(t, 3, lru.size)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "incr keeps ttl of existing key",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				_, _, err := lru.Incr("foo", 1, time.Second)
+				require.NoError(t, err)
+
+				_, _, err = lru.Incr("foo", 1, time.Hour)
+				require.NoError(t, err)
+
+				now = now.Add(time.Second)
+				_, _, ok := lru.Get("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				require.Equal(t, 0, lru.size)
+				require.Equal(t, 0, lru.expiry.Len())
+			},
+		},
+		{
+			name: "incr in goroutines",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				var wg sync.WaitGroup
+				for i := 0; i < 100; i++ {
+					wg.Add(1)
+
+					go func() {
+						defer wg.Done()
+
+						_, _, err := lru.Incr("foo", 1, 0)
+						require.NoError(t, err)
+					}()
+				}
+
+				wg.Wait()
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "foo", val: []byte("100")},
+				}
+
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "listener notified about removals",
+			cap:  2,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				var removed []removal
+				lru.notifier.listeners = append(lru.notifier.listeners, func(key string, val []byte, reason Reason) {
+					removed = append(removed, removal{key: key, val: val, reason: reason})
+
+					// listeners are called outside the lock.
+					lru.Len()
+				})
+
+				lru.Put("foo", []byte("bar"), time.Second)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("baz", []byte("qux"), 0)
+				require.Equal(t, []removal{{key: "foo", val: []byte("bar"), reason: ReasonCapacity}}, removed)
+
+				lru.Put("qux", []byte("quux"), time.Second)
+				now = now.Add(time.Second)
+				require.Equal(t, 1, lru.DeleteExpired())
+				require.True(t, lru.Delete("baz"))
+
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("foo", []byte("baz"), 0)
+				_, _, err := lru.Incr("counter", 1, 0)
+				require.NoError(t, err)
+				_, _, err = lru.Incr("counter", 1, 0)
+				require.NoError(t, err)
+				require.Equal(t, 1, lru.Flush("f"))
+				require.Equal(t, 1, lru.Flush(""))
+
+				require.Equal(t, []removal{
+					{key: "foo", val: []byte("bar"), reason: ReasonCapacity},
+					{key: "bar", val: []byte("baz"), reason: ReasonCapacity},
+					{key: "qux", val: []byte("quux"), reason: ReasonExpired},
+					{key: "baz", val: []byte("qux"), reason: ReasonDeleted},
+					{key: "foo", val: []byte("bar"), reason: ReasonReplaced},
+					{key: "counter", val: []byte("1"), reason: ReasonReplaced},
+					{key: "foo", val: []byte("baz"), reason: ReasonFlushed},
+					{key: "counter", val: []byte("2"), reason: ReasonFlushed},
+				}, removed)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				require.Equal(t, 0, lru.size)
+				require.Empty(t, lru.notifier.pending)
+			},
+		},
+		{
+			name: "scan",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("user:3", []byte("c"), 0)
+				lru.Put("user:1", []byte("a"), 0)
+				lru.Put("order:1", []byte("x"), 0)
+				lru.Put("user:2", []byte("b"), time.Second)
+				lru.Put("user:4", []byte("d"), 0)
+
+				entries, next := lru.Scan("user:", "", 2)
+				require.Equal(t, []Entry{
+					{Key: "user:1", Value: []byte("a"), Version: 2},
+					{Key: "user:2", Value: []byte("b"), Version: 4},
+				}, entries)
+				require.Equal(t, "user:2", next)
+
+				now = now.Add(time.Second)
+				lru.Put("user:0", []byte("z"), 0)
+
+				entries, next = lru.Scan("user:", next, 2)
+				require.Equal(t, []Entry{
+					{Key: "user:3", Value: []byte("c"), Version: 1},
+					{Key: "user:4", Value: []byte("d"), Version: 5},
+				}, entries)
+				require.Equal(t, "", next)
+
+				entries, next = lru.Scan("", "", 0)
+				require.Len(t, entries, 5)
+				require.Equal(t, "", next)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "user:0", val: []byte("z")},
+					{key: "user:4", val: []byte("d")},
+					{key: "user:2", val: []byte("b")},
+					{key: "order:1", val: []byte("x")},
+					{key: "user:1", val: []byte("a")},
+					{key: "user:3", val: []byte("c")},
+				}
+
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "memory budget",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				lru.maxBytes = 3 * (entryOverhead + 6)
+
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("baz", []byte("qux"), 0)
+				require.Equal(t, lru.maxBytes, lru.Stats().Memory)
+
+				lru.Put("qux", []byte("quux"), 0)
+				require.Equal(t, uint64(2), lru.evictions)
+
+				lru.Put("baz", bytes.Repeat([]byte("x"), 200), 0)
+				require.Equal(t, uint64(3), lru.evictions)
+
+				// the entry larger than the budget is evicted right away,
+				// the rest of the cache is kept.
+				lru.Put("big", bytes.Repeat([]byte("x"), 500), 0)
+				_, _, ok := lru.Get("big")
+				require.False(t, ok)
+
+				stats := lru.Stats()
+				require.Equal(t, uint32(1), stats.Items)
+				require.Equal(t, uint64(203+entryOverhead), stats.Memory)
+				require.Equal(t, lru.maxBytes, stats.MaxBytes)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "baz", val: bytes.Repeat([]byte("x"), 200)},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.Equal(t, uint64(4), lru.evictions)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "entry larger than the budget",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				var reasons []Reason
+				lru.maxBytes = 1000
+				lru.notifier.listeners = append(lru.notifier.listeners, func(_ string, _ []byte, reason Reason) {
+					reasons = append(reasons, reason)
+				})
+
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("big", make([]byte, 5000), 0)
+				require.Equal(t, []Reason{ReasonCapacity}, reasons)
+
+				// the oversized update evicts the previous value of the key.
+				lru.Put("foo", make([]byte, 5000), 0)
+				_, _, ok := lru.Get("foo")
+				require.False(t, ok)
+				require.Equal(t, []Reason{ReasonCapacity, ReasonCapacity}, reasons)
+
+				stats := lru.Stats()
+				require.Equal(t, uint32(1), stats.Items)
+				require.Equal(t, uint64(2), stats.Evictions)
+				require.LessOrEqual(t, stats.Memory, stats.MaxBytes)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "bar", val: []byte("baz")},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "snapshot and restore",
+			cap:  3,
+			operate: func(t *testing.T, lru *lru) {
+				source := NewLRU(3)
+				source.Put("foo", []byte("bar"), time.Hour)
+				source.Put("bar", []byte("baz"), time.Second)
+				source.Put("baz", []byte("qux"), 0)
+				source.Get("foo")
+
+				records := source.Snapshot()
+				require.Len(t, records, 3)
+				for i, key := range []string{"bar", "baz", "foo"} {
+					require.Equal(t, key, records[i].Key)
+				}
+
+				require.Equal(t, uint64(2), records[0].Version)
+				require.True(t, records[1].ExpiresAt.IsZero())
+				require.False(t, records[2].ExpiresAt.IsZero())
+
+				now := time.Now().Add(time.Minute)
+				lru.now = func() time.Time { return now }
+				lru.Restore(records)
+
+				require.Equal(t, uint32(2), lru.Len())
+				require.Equal(t, uint64(3), lru.version)
+				require.Equal(t, records[1:], lru.Snapshot())
+				require.Equal(t, uint64(4), lru.Put("qux", []byte("quux"), 0))
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "qux", val: []byte("quux")},
+					{key: "foo", val: []byte("bar")},
+					{key: "baz", val: []byte("qux")},
+				}
+
+				require.Equal(t, 3, lru.size)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "stats",
+			cap:  2,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("baz", []byte("quux"), time.Second)
+				lru.Put("bar", []byte("b"), 0)
+				require.Equal(t, Stats{Items: 2, Bytes: 11, Memory: 267, Capacity: 2, Evictions: 1}, lru.Stats())
+
+				now = now.Add(time.Second)
+				require.Equal(t, Stats{Items: 1, Bytes: 4, Memory: 132, Capacity: 2, Evictions: 1, Expirations: 1}, lru.Stats())
+
+				_, _, err := lru.Incr("bar", 100, 0)
+				require.Error(t, err)
+				_, _, err = lru.Incr("counter", 100, 0)
+				require.NoError(t, err)
+				_, _, err = lru.Incr("counter", 900, 0)
+				require.NoError(t, err)
+				require.True(t, lru.Delete("bar"))
+				require.Equal(t, Stats{Items: 1, Bytes: 11, Memory: 139, Capacity: 2, Evictions: 1, Expirations: 1}, lru.Stats())
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				require.Equal(t, 1, lru.size)
+			},
+		},
+		{
+			name: "flush prefix",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				lru.Put("user:1", []byte("a"), time.Hour)
+				lru.Put("order:1", []byte("x"), 0)
+				lru.Put("user:2", []byte("b"), 0)
+				require.Equal(t, 2, lru.Flush("user:"))
+				require.Equal(t, 0, lru.Flush("user:"))
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "order:1", val: []byte("x")},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.Equal(t, 0, lru.expiry.Len())
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "flush all",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				lru.Put("foo", []byte("bar"), time.Hour)
+				version := lru.Put("bar", []byte("baz"), 0)
+				require.Equal(t, 2, lru.Flush(""))
+
+				_, ok := lru.CompareAndSwap("bar", version, []byte("qux"), 0)
+				require.False(t, ok)
+
+				lru.Put("baz", []byte("qux"), 0)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "baz", val: []byte("qux")},
+				}
+
+				require.Equal(t, 1, lru.size)
+				require.Equal(t, uint64(6), lru.bytes)
+				require.Equal(t, 0, lru.expiry.Len())
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
 		{
 			name: "single capacity",
 			cap:  1,
 			operate: func(t *testing.T, lru *lru) {
-				lru.Put("foo", "bar")
-				lru.Put("bar", "baz")
-				lru.Put("baz", "qux")
+				lru.Put("foo", []byte("bar"), 0)
+				lru.Put("bar", []byte("baz"), 0)
+				lru.Put("baz", []byte("qux"), 0)
 			},
 			verifyInternal: func(t *testing.T, lru *lru) {
 				order := []Node{
-					{key: "baz", val: "qux"},
+					{key: "baz", val: []byte("qux")},
 				}
 
 				require.Equal(t, 1, lru.size)
 				require.True(t, isValidOrder(order, lru.head))
 			},
 		},
+		{
+			name: "peek doesn't affect the order",
+			cap:  10,
+			operate: func(t *testing.T, lru *lru) {
+				now := time.Unix(0, 0)
+				lru.now = func() time.Time { return now }
+
+				lru.Put("foo", []byte("bar"), time.Second)
+				lru.Put("bar", []byte("baz"), 0)
+
+				r, ok := lru.Peek("foo")
+				require.True(t, ok)
+				require.Equal(t, Record{Key: "foo", Value: []byte("bar"), Version: 1, ExpiresAt: now.Add(time.Second)}, r)
+
+				_, ok = lru.Peek("baz")
+				require.False(t, ok)
+
+				now = now.Add(time.Second)
+				_, ok = lru.Peek("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				order := []Node{
+					{key: "bar", val: []byte("baz")},
+					{key: "foo", val: []byte("bar")},
+				}
+
+				require.Equal(t, 2, lru.size)
+				require.True(t, isValidOrder(order, lru.head))
+			},
+		},
+		{
+			name: "zero capacity",
+			cap:  0,
+			operate: func(t *testing.T, lru *lru) {
+				require.Equal(t, uint64(1), lru.Put("foo", []byte("bar"), 0))
+				_, _, ok := lru.Get("foo")
+				require.False(t, ok)
+
+				val, _, err := lru.Incr("counter", 1, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), val)
+				require.Equal(t, Stats{Evictions: 2}, lru.Stats())
+			},
+			verifyInternal: func(t *testing.T, lru *lru) {
+				require.Equal(t, 0, lru.size)
+				require.Zero(t, lru.list.len)
+				require.Empty(t, lru.cache)
+			},
+		},
 		{
 			name: "in goroutines",
 			cap:  100,
@@ -142,7 +810,7 @@ func TestLru_Get(t *testing.T) {
 					go func(i int) {
 						defer wg.Done()
 
-						lru.Put(strconv.Itoa(i), strconv.Itoa(i))
+						lru.Put(strconv.Itoa(i), []byte(strconv.Itoa(i)), 0)
 					}(i)
 				}
 
@@ -151,9 +819,9 @@ func TestLru_Get(t *testing.T) {
 			verifyInternal: func(t *testing.T, lru *lru) {
 				require.Equal(t, lru.cap, lru.size)
 				var (
-					ans = make([]string, 0, lru.cap)
+					ans = make([][]byte, 0, lru.cap)
 					wg  sync.WaitGroup
-					ch  = make(chan string, lru.cap/5)
+					ch  = make(chan []byte, lru.cap/5)
 				)
 
 				for i := 0; i < lru.cap; i++ {
@@ -162,7 +830,7 @@ func TestLru_Get(t *testing.T) {
 					go func(i int) {
 						defer wg.Done()
 
-						val, ok := lru.Get(strconv.Itoa(i))
+						val, _, ok := lru.Get(strconv.Itoa(i))
 						require.True(t, ok)
 						ch <- val
 					}(i)
@@ -0,0 +1,37 @@
+package eviction
+
+type Option func(*options)
+
+type options struct {
+	listeners []Listener
+	maxBytes  uint64
+	samples   int
+}
+
+// WithMaxBytes limits the memory, used by the cache, keys are evicted until
+// the memory usage fits the budget, see Stats.Memory for the accounting.
+//
+// Capacity still limits the number of keys, zero budget means no limit,
+// entries larger than the budget are evicted right away.
+func WithMaxBytes(n uint64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// WithSamples sets the number of keys, which are sampled to find the
+// victim by the sampled LRU, more samples make it closer to the exact LRU.
+func WithSamples(k int) Option {
+	return func(o *options) {
+		o.samples = k
+	}
+}
+
+func newOptions(opts []Option) *options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &o
+}
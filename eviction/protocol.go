@@ -0,0 +1,95 @@
+package eviction
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+)
+
+type AlgorithmType string
+
+const (
+	LRUAlgorithm        AlgorithmType = "lru"
+	LFUAlgorithm        AlgorithmType = "lfu"
+	ARCAlgorithm        AlgorithmType = "arc"
+	TinyLFUAlgorithm    AlgorithmType = "tinylfu"
+	SampledLRUAlgorithm AlgorithmType = "sampled"
+	ShardedLRUAlgorithm AlgorithmType = "sharded-lru"
+)
+
+var (
+	ErrAlgorithmAlreadyRegistered = errors.New("eviction algorithm already registered")
+	ErrUnknownAlgorithm           = errors.New("unknown eviction algorithm")
+)
+
+// Constructor creates the policy with the given capacity, it's the
+// signature of NewLRU and other built-in constructors.
+type Constructor func(capacity int, opts ...Option) Algorithm
+
+var (
+	registryMx sync.RWMutex
+	registry   = map[AlgorithmType]Constructor{
+		LRUAlgorithm:        NewLRU,
+		LFUAlgorithm:        NewLFU,
+		ARCAlgorithm:        NewARC,
+		TinyLFUAlgorithm:    NewTinyLFU,
+		SampledLRUAlgorithm: NewSampledLRU,
+		ShardedLRUAlgorithm: func(capacity int, opts ...Option) Algorithm {
+			return NewShardedLRU(4*runtime.GOMAXPROCS(0), capacity, opts...)
+		},
+	}
+)
+
+// Register makes the policy available for NewAlgo, it's usually called
+// from the init function of the package, which implements the policy.
+func Register(algo AlgorithmType, constructor Constructor) error {
+	registryMx.Lock()
+	defer registryMx.Unlock()
+
+	if _, ok := registry[algo]; ok {
+		return fmt.Errorf("%w: %s", ErrAlgorithmAlreadyRegistered, algo)
+	}
+
+	registry[algo] = constructor
+	return nil
+}
+
+// Algorithms returns the sorted types of all registered policies.
+func Algorithms() []AlgorithmType {
+	registryMx.RLock()
+	defer registryMx.RUnlock()
+
+	types := make([]AlgorithmType, 0, len(registry))
+	for algo := range registry {
+		types = append(types, algo)
+	}
+
+	slices.Sort(types)
+	return types
+}
+
+// Validate returns an error, if the policy isn't registered.
+func (a AlgorithmType) Validate() error {
+	registryMx.RLock()
+	defer registryMx.RUnlock()
+
+	if _, ok := registry[a]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownAlgorithm, a)
+	}
+
+	return nil
+}
+
+func NewAlgo(algo AlgorithmType, capacity int, opts ...Option) (Algorithm, error) {
+	registryMx.RLock()
+	constructor, ok := registry[algo]
+	registryMx.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, algo)
+	}
+
+	return constructor(capacity, opts...), nil
+}
@@ -0,0 +1,90 @@
+package eviction
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewAlgo(t *testing.T) {
+	testcases := []struct {
+		algo          AlgorithmType
+		expectedType  Algorithm
+		expectedError error
+	}{
+		{
+			algo:         LRUAlgorithm,
+			expectedType: &lru{},
+		},
+		{
+			algo:         LFUAlgorithm,
+			expectedType: &lfu{},
+		},
+		{
+			algo:         ARCAlgorithm,
+			expectedType: &arc{},
+		},
+		{
+			algo:         TinyLFUAlgorithm,
+			expectedType: &tinyLFU{},
+		},
+		{
+			algo:         SampledLRUAlgorithm,
+			expectedType: &sampled{},
+		},
+		{
+			algo:         ShardedLRUAlgorithm,
+			expectedType: &sharded{},
+		},
+		{
+			algo:          AlgorithmType("unknown"),
+			expectedType:  nil,
+			expectedError: fmt.Errorf("unknown eviction algorithm: %s", "unknown"),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(string(tc.algo), func(t *testing.T) {
+			algo, err := NewAlgo(tc.algo, 10)
+			if tc.expectedError != nil {
+				require.EqualError(t, err, tc.expectedError.Error())
+				require.ErrorIs(t, tc.algo.Validate(), ErrUnknownAlgorithm)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, tc.algo.Validate())
+
+				algo.Put("foo", []byte("bar"), 0)
+				val, _, ok := algo.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("bar"), val)
+			}
+
+			require.IsType(t, tc.expectedType, algo)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	const custom AlgorithmType = "custom"
+
+	var created int
+	require.NoError(t, Register(custom, func(capacity int, opts ...Option) Algorithm {
+		created++
+		return NewLRU(capacity, opts...)
+	}))
+	t.Cleanup(func() {
+		registryMx.Lock()
+		defer registryMx.Unlock()
+
+		delete(registry, custom)
+	})
+
+	require.Contains(t, Algorithms(), custom)
+	require.ErrorIs(t, Register(custom, NewLRU), ErrAlgorithmAlreadyRegistered)
+	require.ErrorIs(t, Register(LRUAlgorithm, NewLFU), ErrAlgorithmAlreadyRegistered)
+
+	algo, err := NewAlgo(custom, 10)
+	require.NoError(t, err)
+	require.IsType(t, &lru{}, algo)
+	require.Equal(t, 1, created)
+}
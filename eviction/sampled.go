@@ -0,0 +1,112 @@
+package eviction
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+)
+
+// defaultSamples is the number of sampled keys, it's the default of Redis.
+const defaultSamples = 5
+
+// sampled is the approximated LRU in the style of Redis.
+//
+// Nodes don't form the list, every node keeps the time of the last access
+// instead, so reads update it atomically under the read lock. To evict,
+// the policy samples random keys and picks the least recently used one.
+type sampled struct {
+	*store
+	samples int
+	nodes   []*Node
+	rnd     *rand.Rand
+}
+
+func NewSampledLRU(capacity int, opts ...Option) Algorithm {
+	s := &sampled{
+		samples: defaultSamples,
+		rnd:     rand.New(rand.NewSource(rand.Int63())),
+	}
+
+	if o := newOptions(opts); o.samples > 0 {
+		s.samples = o.samples
+	}
+
+	s.store = newStore(capacity, s, opts)
+	return s
+}
+
+// Get never takes the write lock, expired keys are reported as missing
+// and left to the writers and the sweeper.
+func (s *sampled) Get(key string) ([]byte, uint64, bool) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	node, ok := s.cache[key]
+	if !ok || node.expired(s.now()) {
+		return nil, 0, false
+	}
+
+	s.accessed(node)
+	return node.val, node.version, true
+}
+
+func (s *sampled) added(node *Node) {
+	node.sampleIdx = len(s.nodes)
+	s.nodes = append(s.nodes, node)
+	s.accessed(node)
+}
+
+func (s *sampled) accessed(node *Node) {
+	node.accessedAt.Store(s.now().UnixNano())
+}
+
+func (s *sampled) removed(node *Node) {
+	last := s.nodes[len(s.nodes)-1]
+	last.sampleIdx = node.sampleIdx
+	s.nodes[node.sampleIdx] = last
+	s.nodes = s.nodes[:len(s.nodes)-1]
+}
+
+// victim returns the least recently used node among the sampled ones,
+// the same node may be sampled twice, the node of the key is skipped.
+func (s *sampled) victim(key string) *Node {
+	var (
+		n      = len(s.nodes)
+		skip   = -1
+		oldest *Node
+	)
+
+	if node, ok := s.cache[key]; ok {
+		skip = node.sampleIdx
+		n--
+	}
+
+	for i := 0; i < s.samples; i++ {
+		idx := s.rnd.Intn(n)
+		if skip >= 0 && idx >= skip {
+			idx++
+		}
+
+		node := s.nodes[idx]
+		if oldest == nil || node.accessedAt.Load() < oldest.accessedAt.Load() {
+			oldest = node
+		}
+	}
+
+	return oldest
+}
+
+func (s *sampled) reset() {
+	s.nodes = nil
+}
+
+func (s *sampled) walk(fn func(node *Node)) {
+	nodes := slices.Clone(s.nodes)
+	slices.SortFunc(nodes, func(a, b *Node) int {
+		return cmp.Compare(a.accessedAt.Load(), b.accessedAt.Load())
+	})
+
+	for _, node := range nodes {
+		fn(node)
+	}
+}
@@ -0,0 +1,262 @@
+package eviction
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSampled_Get(t *testing.T) {
+	testCases := []struct {
+		name    string
+		cap     int
+		operate func(t *testing.T, s *sampled)
+	}{
+		{
+			name: "get from empty cache",
+			cap:  10,
+			operate: func(t *testing.T, s *sampled) {
+				_, _, ok := s.Get("foo")
+				require.False(t, ok)
+				require.Empty(t, s.nodes)
+			},
+		},
+		{
+			name: "get updates access time",
+			cap:  10,
+			operate: func(t *testing.T, s *sampled) {
+				now := time.Unix(0, 0)
+				s.now = func() time.Time { return now }
+
+				version := s.Put("foo", []byte("bar"), 0)
+				require.Equal(t, int64(0), s.cache["foo"].accessedAt.Load())
+
+				now = now.Add(time.Second)
+				val, got, ok := s.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("bar"), val)
+				require.Equal(t, version, got)
+				require.Equal(t, now.UnixNano(), s.cache["foo"].accessedAt.Load())
+			},
+		},
+		{
+			name: "get doesn't take the write lock",
+			cap:  10,
+			operate: func(t *testing.T, s *sampled) {
+				s.Put("foo", []byte("bar"), 0)
+
+				s.mx.RLock()
+				defer s.mx.RUnlock()
+
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+
+					s.Get("foo")
+				}()
+
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					require.Fail(t, "get is blocked by the reader")
+				}
+			},
+		},
+		{
+			name: "evict least recently used",
+			cap:  2,
+			operate: func(t *testing.T, s *sampled) {
+				now := time.Unix(0, 0)
+				s.now = func() time.Time { return now }
+				s.samples = 64
+
+				s.Put("foo", []byte("bar"), 0)
+				now = now.Add(time.Second)
+				s.Put("bar", []byte("baz"), 0)
+				now = now.Add(time.Second)
+				s.Get("foo")
+				now = now.Add(time.Second)
+				s.Put("baz", []byte("qux"), 0)
+
+				_, _, ok := s.Get("bar")
+				require.False(t, ok)
+				require.Equal(t, 2, s.size)
+				require.Len(t, s.nodes, 2)
+				require.Equal(t, uint64(1), s.evictions)
+			},
+		},
+		{
+			name: "delete keeps sampled nodes consistent",
+			cap:  10,
+			operate: func(t *testing.T, s *sampled) {
+				for i := 0; i < 10; i++ {
+					key := strconv.Itoa(i)
+					s.Put(key, []byte(key), 0)
+				}
+
+				require.True(t, s.Delete("0"))
+				require.True(t, s.Delete("5"))
+				require.Equal(t, 1, s.Flush("9"))
+
+				require.Len(t, s.nodes, 7)
+				for i, node := range s.nodes {
+					require.Equal(t, i, node.sampleIdx)
+					require.Equal(t, node, s.cache[node.key])
+				}
+
+				require.Equal(t, 7, s.Flush(""))
+				require.Empty(t, s.nodes)
+			},
+		},
+		{
+			name: "expired on get",
+			cap:  10,
+			operate: func(t *testing.T, s *sampled) {
+				now := time.Unix(0, 0)
+				s.now = func() time.Time { return now }
+
+				s.Put("foo", []byte("bar"), time.Second)
+				now = now.Add(time.Second)
+
+				_, _, ok := s.Get("foo")
+				require.False(t, ok)
+				require.Equal(t, uint32(0), s.Len())
+				require.Equal(t, uint64(1), s.expired)
+				require.Empty(t, s.nodes)
+			},
+		},
+		{
+			name: "listener notified about removals",
+			cap:  1,
+			operate: func(t *testing.T, s *sampled) {
+				var removed []removal
+				s.notifier.listeners = append(s.notifier.listeners, func(key string, val []byte, reason Reason) {
+					removed = append(removed, removal{key: key, val: val, reason: reason})
+				})
+
+				s.Put("foo", []byte("bar"), 0)
+				s.Put("bar", []byte("baz"), 0)
+				s.Put("bar", []byte("qux"), 0)
+
+				require.Equal(t, []removal{
+					{key: "foo", val: []byte("bar"), reason: ReasonCapacity},
+					{key: "bar", val: []byte("baz"), reason: ReasonReplaced},
+				}, removed)
+			},
+		},
+		{
+			name: "growing update doesn't evict the key",
+			cap:  10,
+			operate: func(t *testing.T, s *sampled) {
+				s.maxBytes = 2*entryOverhead + 100
+				s.samples = 1
+
+				// the single sample hits the updated key in half of the
+				// evictions, if it isn't skipped.
+				for i := 0; i < 32; i++ {
+					s.Put("a", []byte("x"), 0)
+					s.Put("b", []byte("y"), 0)
+					s.Put("a", bytes.Repeat([]byte("x"), 150), 0)
+
+					val, _, ok := s.Get("a")
+					require.True(t, ok)
+					require.Len(t, val, 150)
+					require.Equal(t, 1, s.size)
+				}
+
+				require.Equal(t, uint64(32), s.evictions)
+			},
+		},
+		{
+			name: "in goroutines",
+			cap:  100,
+			operate: func(t *testing.T, s *sampled) {
+				var wg sync.WaitGroup
+				for g := 0; g < 8; g++ {
+					wg.Add(1)
+
+					go func(g int) {
+						defer wg.Done()
+
+						r := rand.New(rand.NewSource(int64(g)))
+						for i := 0; i < 1000; i++ {
+							key := strconv.Itoa(r.Intn(300))
+							if _, _, ok := s.Get(key); !ok {
+								s.Put(key, []byte(key), 0)
+							}
+						}
+					}(g)
+				}
+
+				wg.Wait()
+				require.Equal(t, 100, s.size)
+				require.Len(t, s.nodes, 100)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			algo := NewSampledLRU(tc.cap)
+
+			ep, _ := algo.(*sampled)
+			tc.operate(t, ep)
+		})
+	}
+}
+
+// hitRatio runs the read-through workload with zipf distributed keys.
+func hitRatio(algo Algorithm, keys uint64, ops int) float64 {
+	var (
+		r    = rand.New(rand.NewSource(42))
+		zipf = rand.NewZipf(r, 1.1, 1, keys-1)
+		hits int
+	)
+
+	for i := 0; i < ops; i++ {
+		key := strconv.FormatUint(zipf.Uint64(), 10)
+		if _, _, ok := algo.Get(key); ok {
+			hits++
+			continue
+		}
+
+		algo.Put(key, []byte(key), 0)
+	}
+
+	return float64(hits) / float64(ops)
+}
+
+func TestSampled_HitRatio(t *testing.T) {
+	const (
+		keys = 10000
+		ops  = 200000
+	)
+
+	testCases := []struct {
+		name      string
+		cap       int
+		samples   int
+		tolerance float64
+	}{
+		{name: "default samples", cap: 1000, samples: defaultSamples, tolerance: 0.02},
+		{name: "more samples", cap: 1000, samples: 16, tolerance: 0.01},
+		{name: "small cache", cap: 100, samples: defaultSamples, tolerance: 0.02},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				exact  = hitRatio(NewLRU(tc.cap), keys, ops)
+				approx = hitRatio(NewSampledLRU(tc.cap, WithSamples(tc.samples)), keys, ops)
+			)
+
+			t.Logf("lru: %.4f, sampled: %.4f", exact, approx)
+			require.Greater(t, approx, 0.0)
+			require.InDelta(t, exact, approx, tc.tolerance)
+		})
+	}
+}
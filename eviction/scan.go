@@ -0,0 +1,78 @@
+package eviction
+
+import (
+	"container/heap"
+	"slices"
+	"strings"
+	"time"
+)
+
+// pageKeys is a max-heap of the keys of the page, the largest key is
+// replaced, when the smaller one is found, so the page is collected in
+// O(n log limit) instead of sorting all matching keys.
+type pageKeys []string
+
+func (p pageKeys) Len() int { return len(p) }
+
+func (p pageKeys) Less(i, j int) bool { return p[i] > p[j] }
+
+func (p pageKeys) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+func (p *pageKeys) Push(x any) {
+	key, _ := x.(string)
+	*p = append(*p, key)
+}
+
+func (p *pageKeys) Pop() any {
+	old := *p
+	key := old[len(old)-1]
+	*p = old[:len(old)-1]
+	return key
+}
+
+// scan implements Algorithm.Scan over the cache map, it's shared between
+// the policies, because the page doesn't depend on the eviction order.
+//
+// cursor is a key, not an offset in the map, so keys, which exist during
+// the whole scan, are returned exactly once, even if other keys are added
+// or removed between the pages.
+func scan(cache map[string]*Node, now time.Time, prefix, cursor string, limit int) ([]Entry, string) {
+	// one more key is kept to know, whether the page is the last one.
+	var keys = make(pageKeys, 0)
+	if limit > 0 {
+		keys = make(pageKeys, 0, limit+1)
+	}
+
+	for key, node := range cache {
+		if key <= cursor || !strings.HasPrefix(key, prefix) || node.expired(now) {
+			continue
+		}
+
+		switch {
+		case limit <= 0 || len(keys) <= limit:
+			keys = append(keys, key)
+			if limit > 0 && len(keys) == limit+1 {
+				heap.Init(&keys)
+			}
+		case key < keys[0]:
+			keys[0] = key
+			heap.Fix(&keys, 0)
+		}
+	}
+
+	slices.Sort(keys)
+
+	var next string
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+		next = keys[limit-1]
+	}
+
+	var entries = make([]Entry, 0, len(keys))
+	for _, key := range keys {
+		node := cache[key]
+		entries = append(entries, Entry{Key: key, Value: node.val, Version: node.version})
+	}
+
+	return entries, next
+}
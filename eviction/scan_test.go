@@ -0,0 +1,77 @@
+package eviction
+
+import (
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestScan_Pages(t *testing.T) {
+	var (
+		cache = make(map[string]*Node)
+		now   = time.Now()
+		want  = make([]string, 0)
+	)
+
+	for _, i := range rand.New(rand.NewSource(1)).Perm(1000) {
+		key := "key" + strconv.Itoa(i)
+		node := &Node{key: key}
+		switch {
+		case i%7 == 0:
+			node.expiresAt = now
+		case i%5 == 0:
+			key = "other" + strconv.Itoa(i)
+		default:
+			want = append(want, key)
+		}
+
+		cache[key] = node
+	}
+
+	slices.Sort(want)
+
+	for _, limit := range []int{1, 3, 100, len(want) - 1, len(want), len(want) + 1} {
+		t.Run(strconv.Itoa(limit), func(t *testing.T) {
+			var (
+				cursor string
+				got    = make([]string, 0, len(want))
+			)
+
+			for {
+				entries, next := scan(cache, now, "key", cursor, limit)
+				require.LessOrEqual(t, len(entries), limit)
+				for _, e := range entries {
+					got = append(got, e.Key)
+				}
+
+				if next == "" {
+					break
+				}
+
+				cursor = next
+			}
+
+			require.Equal(t, want, got)
+		})
+	}
+
+	entries, next := scan(cache, now, "key", "", 0)
+	require.Len(t, entries, len(want))
+	require.Empty(t, next)
+}
+
+func BenchmarkScan(b *testing.B) {
+	var cache = make(map[string]*Node)
+	for i := 0; i < 1<<16; i++ {
+		key := strconv.Itoa(i)
+		cache[key] = &Node{key: key}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scan(cache, time.Now(), "", "", 100)
+	}
+}
@@ -0,0 +1,206 @@
+package eviction
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// sharded partitions keys across independently locked segments by the
+// hash of the key, so operations on different segments don't contend.
+//
+// capacity and memory budget are split evenly between the segments, so
+// the eviction order is kept only within the segment.
+type sharded struct {
+	segments []Algorithm
+}
+
+// NewShardedLRU creates the cache of n LRU segments with the total
+// capacity, n is usually a few times larger than GOMAXPROCS.
+//
+// the remainder of the capacity is given to the first segments, so the
+// total capacity is exact, the number of segments doesn't exceed it.
+//
+// the memory budget is split the same way, every segment gets at least
+// entryOverhead bytes, because zero budget of the segment means no limit.
+func NewShardedLRU(n, capacity int, opts ...Option) Algorithm {
+	var o = newOptions(opts)
+
+	n = min(n, capacity)
+	if o.maxBytes > 0 {
+		n = min(n, int(min(o.maxBytes/entryOverhead, math.MaxInt32)))
+	}
+
+	n = max(1, n)
+	s := &sharded{segments: make([]Algorithm, n)}
+
+	for i := range s.segments {
+		segmentOpts := []Option{WithMaxBytes(split(o.maxBytes, uint64(n), uint64(i)))}
+		for _, l := range o.listeners {
+			segmentOpts = append(segmentOpts, WithListener(l))
+		}
+
+		s.segments[i] = NewLRU(int(split(uint64(max(capacity, 0)), uint64(n), uint64(i))), segmentOpts...)
+	}
+
+	return s
+}
+
+// split returns the share of the i-th of n parts of the total, the first
+// total%n parts are larger by one.
+func split(total, n, i uint64) uint64 {
+	share := total / n
+	if i < total%n {
+		share++
+	}
+
+	return share
+}
+
+// segment returns the segment of the key, using the inlined fnv-1a hash
+// to avoid allocations on the hot path.
+func (s *sharded) segment(key string) Algorithm {
+	var h uint32 = 2166136261
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return s.segments[h%uint32(len(s.segments))]
+}
+
+func (s *sharded) Get(key string) ([]byte, uint64, bool) {
+	return s.segment(key).Get(key)
+}
+
+func (s *sharded) Put(key string, val []byte, ttl time.Duration) uint64 {
+	return s.segment(key).Put(key, val, ttl)
+}
+
+func (s *sharded) CompareAndSwap(key string, expected uint64, val []byte, ttl time.Duration) (uint64, bool) {
+	return s.segment(key).CompareAndSwap(key, expected, val, ttl)
+}
+
+func (s *sharded) Incr(key string, delta int64, ttl time.Duration) (int64, uint64, error) {
+	return s.segment(key).Incr(key, delta, ttl)
+}
+
+func (s *sharded) Peek(key string) (Record, bool) {
+	return s.segment(key).Peek(key)
+}
+
+func (s *sharded) Delete(key string) bool {
+	return s.segment(key).Delete(key)
+}
+
+func (s *sharded) DeleteExpired() int {
+	var removed int
+	for _, seg := range s.segments {
+		removed += seg.DeleteExpired()
+	}
+
+	return removed
+}
+
+func (s *sharded) Flush(prefix string) int {
+	var removed int
+	for _, seg := range s.segments {
+		removed += seg.Flush(prefix)
+	}
+
+	return removed
+}
+
+// Scan merges the pages of all segments, every segment returns its first
+// keys after the cursor, so the first limit keys of the merge are the
+// first keys of the whole cache.
+func (s *sharded) Scan(prefix, cursor string, limit int) ([]Entry, string) {
+	var (
+		entries = make([]Entry, 0)
+		more    bool
+	)
+
+	for _, seg := range s.segments {
+		page, next := seg.Scan(prefix, cursor, limit)
+		entries = append(entries, page...)
+		more = more || next != ""
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	if limit <= 0 || (len(entries) <= limit && !more) {
+		return entries, ""
+	}
+
+	entries = entries[:min(limit, len(entries))]
+	return entries, entries[len(entries)-1].Key
+}
+
+func (s *sharded) Len() uint32 {
+	var total uint32
+	for _, seg := range s.segments {
+		total += seg.Len()
+	}
+
+	return total
+}
+
+func (s *sharded) Stats() Stats {
+	var total Stats
+	for _, seg := range s.segments {
+		stats := seg.Stats()
+		total.Items += stats.Items
+		total.Bytes += stats.Bytes
+		total.Memory += stats.Memory
+		total.MaxBytes += stats.MaxBytes
+		total.Capacity += stats.Capacity
+		total.Evictions += stats.Evictions
+		total.Expirations += stats.Expirations
+	}
+
+	return total
+}
+
+// Snapshot concatenates the snapshots of the segments, the order is kept
+// within every segment, it's enough, because keys are restored to the
+// same segments.
+func (s *sharded) Snapshot() []Record {
+	var records = make([]Record, 0)
+	for _, seg := range s.segments {
+		records = append(records, seg.Snapshot()...)
+	}
+
+	return records
+}
+
+func (s *sharded) Restore(records []Record) {
+	var groups = make(map[Algorithm][]Record, len(s.segments))
+	for _, r := range records {
+		seg := s.segment(r.Key)
+		groups[seg] = append(groups[seg], r)
+	}
+
+	for seg, group := range groups {
+		seg.Restore(group)
+	}
+}
+
+// Version returns the largest counter of the segments, every segment
+// has its own counter.
+func (s *sharded) Version() uint64 {
+	var version uint64
+	for _, seg := range s.segments {
+		version = max(version, seg.Version())
+	}
+
+	return version
+}
+
+func (s *sharded) AdvanceVersion(version uint64) {
+	for _, seg := range s.segments {
+		seg.AdvanceVersion(version)
+	}
+}
@@ -0,0 +1,304 @@
+package eviction
+
+import (
+	"github.com/stretchr/testify/require"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSharded_Get(t *testing.T) {
+	testCases := []struct {
+		name     string
+		segments int
+		cap      int
+		operate  func(t *testing.T, s *sharded)
+	}{
+		{
+			name:     "get from empty cache",
+			segments: 4,
+			cap:      10,
+			operate: func(t *testing.T, s *sharded) {
+				_, _, ok := s.Get("foo")
+				require.False(t, ok)
+				require.Equal(t, uint32(0), s.Len())
+			},
+		},
+		{
+			name:     "key is routed to the same segment",
+			segments: 8,
+			cap:      80,
+			operate: func(t *testing.T, s *sharded) {
+				for i := 0; i < 50; i++ {
+					key := strconv.Itoa(i)
+					version := s.Put(key, []byte(key), 0)
+
+					val, got, ok := s.segment(key).Get(key)
+					require.True(t, ok)
+					require.Equal(t, []byte(key), val)
+					require.Equal(t, version, got)
+				}
+
+				require.Equal(t, uint32(50), s.Len())
+				require.True(t, s.Delete("7"))
+				require.False(t, s.Delete("7"))
+				require.Equal(t, uint32(49), s.Len())
+			},
+		},
+		{
+			name:     "capacity is split between segments",
+			segments: 4,
+			cap:      8,
+			operate: func(t *testing.T, s *sharded) {
+				for i := 0; i < 100; i++ {
+					key := strconv.Itoa(i)
+					s.Put(key, []byte(key), 0)
+				}
+
+				stats := s.Stats()
+				require.Equal(t, uint32(8), stats.Items)
+				require.Equal(t, uint64(8), stats.Capacity)
+				require.Equal(t, uint64(92), stats.Evictions)
+				for _, seg := range s.segments {
+					require.Equal(t, uint32(2), seg.Len())
+				}
+			},
+		},
+		{
+			name:     "capacity remainder is given to the first segments",
+			segments: 4,
+			cap:      10,
+			operate: func(t *testing.T, s *sharded) {
+				require.Equal(t, uint64(10), s.Stats().Capacity)
+				require.Equal(t, []uint64{3, 3, 2, 2}, []uint64{
+					s.segments[0].Stats().Capacity,
+					s.segments[1].Stats().Capacity,
+					s.segments[2].Stats().Capacity,
+					s.segments[3].Stats().Capacity,
+				})
+			},
+		},
+		{
+			name:     "segments don't exceed the capacity",
+			segments: 4,
+			cap:      2,
+			operate: func(t *testing.T, s *sharded) {
+				require.Len(t, s.segments, 2)
+				for i := 0; i < 10; i++ {
+					key := strconv.Itoa(i)
+					s.Put(key, []byte(key), 0)
+				}
+
+				require.Equal(t, Stats{Items: 2, Bytes: 4, Memory: 4 + 2*entryOverhead, Capacity: 2, Evictions: 8}, s.Stats())
+			},
+		},
+		{
+			name:     "memory budget smaller than the segments",
+			segments: 16,
+			cap:      1000,
+			operate: func(t *testing.T, s *sharded) {
+				for _, maxBytes := range []uint64{10, 3 * entryOverhead} {
+					*s = *NewShardedLRU(16, 1000, WithMaxBytes(maxBytes)).(*sharded)
+					require.Len(t, s.segments, max(1, int(maxBytes/entryOverhead)))
+
+					for i := 0; i < 1000; i++ {
+						s.Put(strconv.Itoa(i), []byte("x"), 0)
+					}
+
+					stats := s.Stats()
+					require.Equal(t, maxBytes, stats.MaxBytes)
+					require.LessOrEqual(t, stats.Memory, maxBytes)
+				}
+			},
+		},
+		{
+			name:     "compare and swap and incr",
+			segments: 4,
+			cap:      10,
+			operate: func(t *testing.T, s *sharded) {
+				version, ok := s.CompareAndSwap("foo", 0, []byte("bar"), 0)
+				require.True(t, ok)
+				_, ok = s.CompareAndSwap("foo", version, []byte("baz"), 0)
+				require.True(t, ok)
+
+				val, _, err := s.Incr("counter", 5, 0)
+				require.NoError(t, err)
+				require.Equal(t, int64(5), val)
+				_, _, err = s.Incr("foo", 1, 0)
+				require.ErrorIs(t, err, ErrNotInteger)
+			},
+		},
+		{
+			name:     "scan across segments",
+			segments: 4,
+			cap:      100,
+			operate: func(t *testing.T, s *sharded) {
+				for i := 0; i < 30; i++ {
+					key := "user:" + strconv.Itoa(100+i)
+					s.Put(key, []byte(key), 0)
+				}
+
+				s.Put("order:1", []byte("x"), 0)
+
+				var (
+					keys   = make([]string, 0)
+					cursor string
+				)
+
+				for {
+					entries, next := s.Scan("user:", cursor, 7)
+					require.LessOrEqual(t, len(entries), 7)
+					for _, e := range entries {
+						keys = append(keys, e.Key)
+					}
+
+					if next == "" {
+						break
+					}
+
+					cursor = next
+				}
+
+				require.Len(t, keys, 30)
+				for i, key := range keys {
+					require.Equal(t, "user:"+strconv.Itoa(100+i), key)
+				}
+
+				entries, next := s.Scan("", "", 0)
+				require.Len(t, entries, 31)
+				require.Equal(t, "", next)
+			},
+		},
+		{
+			name:     "expire and flush",
+			segments: 4,
+			cap:      100,
+			operate: func(t *testing.T, s *sharded) {
+				now := time.Unix(0, 0)
+				for _, seg := range s.segments {
+					seg.(*lru).now = func() time.Time { return now }
+				}
+
+				for i := 0; i < 10; i++ {
+					s.Put("tmp:"+strconv.Itoa(i), []byte("x"), time.Second)
+					s.Put("user:"+strconv.Itoa(i), []byte("x"), 0)
+					s.Put("order:"+strconv.Itoa(i), []byte("x"), 0)
+				}
+
+				now = now.Add(time.Second)
+				require.Equal(t, 10, s.DeleteExpired())
+				require.Equal(t, 10, s.Flush("user:"))
+				require.Equal(t, 10, s.Flush(""))
+				require.Equal(t, Stats{Capacity: 100, Expirations: 10}, s.Stats())
+			},
+		},
+		{
+			name:     "listeners and memory budget are passed to segments",
+			segments: 2,
+			cap:      100,
+			operate: func(t *testing.T, s *sharded) {
+				var (
+					mx      sync.Mutex
+					removed int
+				)
+
+				*s = *NewShardedLRU(2, 100,
+					WithMaxBytes(2*(entryOverhead+4)),
+					WithListener(func(string, []byte, Reason) {
+						mx.Lock()
+						defer mx.Unlock()
+
+						removed++
+					}),
+				).(*sharded)
+
+				for i := 0; i < 100; i++ {
+					s.Put(strconv.Itoa(100+i), []byte("x"), 0)
+				}
+
+				require.Equal(t, uint64(2*(entryOverhead+4)), s.Stats().MaxBytes)
+				require.LessOrEqual(t, s.Stats().Memory, s.Stats().MaxBytes)
+				require.Equal(t, 100-int(s.Len()), removed)
+			},
+		},
+		{
+			name:     "in goroutines",
+			segments: 16,
+			cap:      1000,
+			operate: func(t *testing.T, s *sharded) {
+				var wg sync.WaitGroup
+				for g := 0; g < 8; g++ {
+					wg.Add(1)
+
+					go func(g int) {
+						defer wg.Done()
+
+						r := rand.New(rand.NewSource(int64(g)))
+						for i := 0; i < 2000; i++ {
+							key := strconv.Itoa(r.Intn(2000))
+							switch r.Intn(10) {
+							case 0:
+								s.Put(key, []byte(key), 0)
+							case 1:
+								s.Delete(key)
+							case 2:
+								_, _, _ = s.Incr("counter", 1, 0)
+							default:
+								if val, _, ok := s.Get(key); ok {
+									require.Equal(t, []byte(key), val)
+								}
+							}
+						}
+					}(g)
+				}
+
+				wg.Wait()
+				require.LessOrEqual(t, s.Len(), uint32(1000))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			algo := NewShardedLRU(tc.segments, tc.cap)
+
+			ep, _ := algo.(*sharded)
+			tc.operate(t, ep)
+		})
+	}
+}
+
+// benchmarkParallel runs the mixed workload of 90% gets and 10% puts,
+// run it with -cpu 1,2,4,8 to see, how the cache scales with GOMAXPROCS.
+func benchmarkParallel(b *testing.B, algo Algorithm) {
+	const keys = 1 << 14
+
+	for i := 0; i < keys; i++ {
+		algo.Put(strconv.Itoa(i), []byte("value"), 0)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			key := strconv.Itoa(r.Intn(keys))
+			if r.Intn(10) == 0 {
+				algo.Put(key, []byte("value"), 0)
+			} else {
+				algo.Get(key)
+			}
+		}
+	})
+}
+
+func BenchmarkLRU_Parallel(b *testing.B) {
+	benchmarkParallel(b, NewLRU(1<<13))
+}
+
+func BenchmarkShardedLRU_Parallel(b *testing.B) {
+	benchmarkParallel(b, NewShardedLRU(64, 1<<13))
+}
@@ -0,0 +1,109 @@
+package eviction
+
+import "hash/fnv"
+
+const (
+	sketchDepth = 4
+
+	// sketchMaxCount is the saturation limit of the counter, 4 bits are
+	// enough to tell hot keys from cold ones.
+	sketchMaxCount = 15
+
+	// sketchResetFactor defines the sample size, after which all counters
+	// are halved, relative to the width of the sketch.
+	sketchResetFactor = 10
+)
+
+// sketch is the count-min sketch, which estimates the access frequency
+// of the keys in the fixed memory.
+//
+// counters are periodically halved, so the frequencies of the keys, which
+// were popular long ago, decay over time.
+type sketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newSketch(capacity int) *sketch {
+	width := 16
+	for width < capacity {
+		width <<= 1
+	}
+
+	s := &sketch{mask: uint64(width - 1), resetAt: width * sketchResetFactor}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+
+	return s
+}
+
+// indexes returns the counter index in every row, derived from the
+// single 64-bit hash of the key.
+func (s *sketch) indexes(key string) [sketchDepth]uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+
+	var (
+		lo, hi = sum & 0xffffffff, sum >> 32
+		idx    [sketchDepth]uint64
+	)
+
+	for i := range idx {
+		idx[i] = (lo + uint64(i)*hi) & s.mask
+	}
+
+	return idx
+}
+
+func (s *sketch) increment(key string) {
+	var added bool
+	for i, idx := range s.indexes(key) {
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+			added = true
+		}
+	}
+
+	if !added {
+		return
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.age()
+	}
+}
+
+// estimate returns the minimal counter of the key, which is never less
+// than the real frequency, taking aging into account.
+func (s *sketch) estimate(key string) uint8 {
+	var est uint8 = sketchMaxCount
+	for i, idx := range s.indexes(key) {
+		est = min(est, s.rows[i][idx])
+	}
+
+	return est
+}
+
+// age halves all counters.
+func (s *sketch) age() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+
+	s.additions /= 2
+}
+
+func (s *sketch) reset() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+
+	s.additions = 0
+}
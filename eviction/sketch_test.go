@@ -0,0 +1,45 @@
+package eviction
+
+import (
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestSketch(t *testing.T) {
+	t.Run("estimate is never less than frequency", func(t *testing.T) {
+		s := newSketch(64)
+		for i := 0; i < 64; i++ {
+			for j := 0; j <= i%8; j++ {
+				s.increment(strconv.Itoa(i))
+			}
+		}
+
+		for i := 0; i < 64; i++ {
+			require.GreaterOrEqual(t, s.estimate(strconv.Itoa(i)), uint8(i%8+1))
+		}
+	})
+
+	t.Run("counters saturate", func(t *testing.T) {
+		s := newSketch(64)
+		for i := 0; i < 100; i++ {
+			s.increment("foo")
+		}
+
+		require.Equal(t, uint8(sketchMaxCount), s.estimate("foo"))
+	})
+
+	t.Run("counters are halved after the sample", func(t *testing.T) {
+		s := newSketch(16)
+		for i := 0; i < 8; i++ {
+			s.increment("foo")
+		}
+
+		require.Equal(t, uint8(8), s.estimate("foo"))
+
+		s.additions = s.resetAt - 1
+		s.increment("foo")
+		require.Equal(t, uint8(4), s.estimate("foo"))
+		require.Equal(t, s.resetAt/2, s.additions)
+	})
+}
@@ -0,0 +1,414 @@
+package eviction
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// policy decides, which key is evicted, when the cache is full.
+//
+// store keeps keys, values, versions and deadlines, and calls the policy
+// under its lock, on every change of the stored nodes.
+type policy interface {
+
+	// added is called, when the new node is stored.
+	added(node *Node)
+
+	// accessed is called, when the node is read or updated.
+	accessed(node *Node)
+
+	// removed is called, when the node is removed for any reason,
+	// including the eviction of the node returned by victim.
+	removed(node *Node)
+
+	// victim returns the node to evict, it's called only for non-empty
+	// cache, right before the node with the given key is added, or after
+	// the node with the given key grows, so the node of the key is never
+	// returned.
+	victim(key string) *Node
+
+	// reset forgets all nodes, it's called on the full flush.
+	reset()
+
+	// walk calls fn for every node from the coldest to the hottest one.
+	walk(fn func(node *Node))
+}
+
+// entryOverhead is the approximate memory, used by the cache for every
+// key besides the key and the value: the node, its links and the map entry.
+const entryOverhead = 128
+
+// store implements Algorithm for any policy.
+type store struct {
+	cap, size int
+	maxBytes  uint64
+	version   uint64
+	bytes     uint64
+	evictions uint64
+	expired   uint64
+	cache     map[string]*Node
+	expiry    expirations
+	mx        sync.RWMutex
+	now       func() time.Time
+	notifier  notifier
+	policy    policy
+}
+
+func newStore(capacity int, p policy, opts []Option) *store {
+	o := newOptions(opts)
+
+	return &store{
+		cap:      capacity,
+		maxBytes: o.maxBytes,
+		cache:    make(map[string]*Node),
+		now:      time.Now,
+		notifier: notifier{listeners: o.listeners},
+		policy:   p,
+	}
+}
+
+// memory returns the memory usage of the cache, compared with the budget.
+func (s *store) memory() uint64 {
+	return s.bytes + uint64(s.size)*entryOverhead
+}
+
+// full reports whether the entry of the given size doesn't fit the cache.
+func (s *store) full(entry uint64) bool {
+	return s.size >= s.cap || (s.maxBytes > 0 && s.memory()+entry > s.maxBytes)
+}
+
+// oversized reports whether the entry doesn't fit the budget even in the
+// empty cache, such entries are never stored.
+func (s *store) oversized(key string, val []byte) bool {
+	return s.maxBytes > 0 && uint64(len(key)+len(val))+entryOverhead > s.maxBytes
+}
+
+// shrink evicts keys after the update of the key, while the memory usage
+// exceeds the budget, the updated key always fits the budget alone.
+func (s *store) shrink(key string) {
+	for s.size > 1 && s.maxBytes > 0 && s.memory() > s.maxBytes {
+		s.evict(key)
+	}
+}
+
+// unlock releases the lock and notifies the listeners about keys, which
+// were removed, while it was held.
+func (s *store) unlock() {
+	notify := s.notifier.flush()
+	s.mx.Unlock()
+	notify()
+}
+
+func (s *store) Get(key string) ([]byte, uint64, bool) {
+	s.mx.Lock()
+	defer s.unlock()
+
+	node, ok := s.lookup(key)
+	if !ok {
+		return nil, 0, false
+	}
+
+	s.policy.accessed(node)
+	return node.val, node.version, true
+}
+
+// lookup returns the node of the key, removing it, if it's expired.
+func (s *store) lookup(key string) (*Node, bool) {
+	node, ok := s.cache[key]
+	if !ok {
+		return nil, false
+	}
+
+	if node.expired(s.now()) {
+		s.remove(node, ReasonExpired)
+		s.expired++
+		return nil, false
+	}
+
+	return node, true
+}
+
+func (s *store) Put(key string, val []byte, ttl time.Duration) uint64 {
+	s.mx.Lock()
+	defer s.unlock()
+
+	return s.putUnsafe(key, val, ttl)
+}
+
+func (s *store) putUnsafe(key string, val []byte, ttl time.Duration) uint64 {
+	s.version++
+
+	// the entry larger than the budget would evict all keys, so it's
+	// evicted right away, the previous value of the key is evicted too.
+	if s.oversized(key, val) {
+		if node, ok := s.cache[key]; ok {
+			s.remove(node, ReasonCapacity)
+		} else {
+			s.notifier.collect(key, val, ReasonCapacity)
+		}
+
+		s.evictions++
+		return s.version
+	}
+
+	if node, ok := s.cache[key]; ok {
+		s.bytes = s.bytes - uint64(len(node.val)) + uint64(len(val))
+		s.notifier.collect(key, node.val, ReasonReplaced)
+		node.val, node.version = val, s.version
+		s.expiry.setDeadline(node, ttl, s.now())
+		s.policy.accessed(node)
+		s.shrink(key)
+		return node.version
+	}
+
+	// the cache without capacity stores nothing, the new key is evicted
+	// right away.
+	if s.cap <= 0 {
+		s.notifier.collect(key, val, ReasonCapacity)
+		s.evictions++
+		return s.version
+	}
+
+	for s.size > 0 && s.full(uint64(len(key)+len(val))+entryOverhead) {
+		s.evict(key)
+	}
+
+	node := &Node{key: key, val: val, version: s.version}
+	s.cache[key] = node
+	s.size++
+	s.bytes += uint64(len(key) + len(val))
+	s.expiry.setDeadline(node, ttl, s.now())
+	s.policy.added(node)
+	return node.version
+}
+
+func (s *store) CompareAndSwap(key string, expected uint64, val []byte, ttl time.Duration) (uint64, bool) {
+	s.mx.Lock()
+	defer s.unlock()
+
+	var current uint64
+	if node, ok := s.lookup(key); ok {
+		current = node.version
+	}
+
+	if current != expected {
+		return current, false
+	}
+
+	return s.putUnsafe(key, val, ttl), true
+}
+
+func (s *store) Incr(key string, delta int64, ttl time.Duration) (int64, uint64, error) {
+	s.mx.Lock()
+	defer s.unlock()
+
+	node, ok := s.lookup(key)
+	if !ok {
+		version := s.putUnsafe(key, []byte(strconv.FormatInt(delta, 10)), ttl)
+		return delta, version, nil
+	}
+
+	current, err := strconv.ParseInt(string(node.val), 10, 64)
+	if err != nil {
+		return 0, 0, ErrNotInteger
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, 0, ErrOverflow
+	}
+
+	val := []byte(strconv.FormatInt(current+delta, 10))
+	if s.oversized(key, val) {
+		return current + delta, s.putUnsafe(key, val, 0), nil
+	}
+
+	s.version++
+	s.bytes = s.bytes - uint64(len(node.val)) + uint64(len(val))
+	s.notifier.collect(key, node.val, ReasonReplaced)
+	node.val, node.version = val, s.version
+	s.policy.accessed(node)
+	s.shrink(key)
+	return current + delta, node.version, nil
+}
+
+func (s *store) Peek(key string) (Record, bool) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	node, ok := s.cache[key]
+	if !ok || node.expired(s.now()) {
+		return Record{}, false
+	}
+
+	return Record{Key: key, Value: node.val, Version: node.version, ExpiresAt: node.expiresAt}, true
+}
+
+func (s *store) Delete(key string) bool {
+	s.mx.Lock()
+	defer s.unlock()
+
+	node, ok := s.lookup(key)
+	if !ok {
+		return false
+	}
+
+	s.remove(node, ReasonDeleted)
+	return true
+}
+
+func (s *store) DeleteExpired() int {
+	s.mx.Lock()
+	defer s.unlock()
+
+	return s.deleteExpiredUnsafe()
+}
+
+func (s *store) deleteExpiredUnsafe() int {
+	var (
+		now     = s.now()
+		removed int
+	)
+
+	for node := s.expiry.nextExpired(now); node != nil; node = s.expiry.nextExpired(now) {
+		s.remove(node, ReasonExpired)
+		s.expired++
+		removed++
+	}
+
+	return removed
+}
+
+// evict removes the victim of the policy to free the space for the key.
+func (s *store) evict(key string) {
+	node := s.policy.victim(key)
+	s.remove(node, ReasonCapacity)
+	s.evictions++
+}
+
+// remove drops the node and notifies the listeners with the reason.
+func (s *store) remove(node *Node, reason Reason) {
+	s.notifier.collect(node.key, node.val, reason)
+	s.policy.removed(node)
+	s.expiry.untrack(node)
+	delete(s.cache, node.key)
+	s.size--
+	s.bytes -= uint64(len(node.key) + len(node.val))
+}
+
+func (s *store) Flush(prefix string) int {
+	s.mx.Lock()
+	defer s.unlock()
+
+	if prefix == "" {
+		for key, node := range s.cache {
+			s.notifier.collect(key, node.val, ReasonFlushed)
+		}
+
+		removed := s.size
+		s.cache = make(map[string]*Node)
+		s.policy.reset()
+		s.expiry = nil
+		s.size, s.bytes = 0, 0
+		return removed
+	}
+
+	var removed int
+	for key, node := range s.cache {
+		if strings.HasPrefix(key, prefix) {
+			s.remove(node, ReasonFlushed)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func (s *store) Scan(prefix, cursor string, limit int) ([]Entry, string) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	return scan(s.cache, s.now(), prefix, cursor, limit)
+}
+
+func (s *store) Len() uint32 {
+	s.mx.Lock()
+	defer s.unlock()
+
+	s.deleteExpiredUnsafe()
+	return uint32(s.size)
+}
+
+func (s *store) Stats() Stats {
+	s.mx.Lock()
+	defer s.unlock()
+
+	s.deleteExpiredUnsafe()
+	return Stats{
+		Items:       uint32(s.size),
+		Bytes:       s.bytes,
+		Memory:      s.memory(),
+		MaxBytes:    s.maxBytes,
+		Capacity:    uint64(s.cap),
+		Evictions:   s.evictions,
+		Expirations: s.expired,
+	}
+}
+
+func (s *store) Snapshot() []Record {
+	s.mx.Lock()
+	defer s.unlock()
+
+	s.deleteExpiredUnsafe()
+
+	records := make([]Record, 0, s.size)
+	s.policy.walk(func(node *Node) {
+		records = append(records, Record{
+			Key:       node.key,
+			Value:     node.val,
+			Version:   node.version,
+			ExpiresAt: node.expiresAt,
+		})
+	})
+
+	return records
+}
+
+func (s *store) Restore(records []Record) {
+	s.mx.Lock()
+	defer s.unlock()
+
+	now := s.now()
+	for _, r := range records {
+		if !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt) {
+			continue
+		}
+
+		// the version is taken from the record, so the counter isn't
+		// moved by the put itself.
+		version := s.version
+		s.putUnsafe(r.Key, r.Value, 0)
+		if node, ok := s.cache[r.Key]; ok {
+			node.version = r.Version
+			s.expiry.setExpiresAt(node, r.ExpiresAt)
+		}
+
+		s.version = max(version, r.Version)
+	}
+}
+
+func (s *store) Version() uint64 {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	return s.version
+}
+
+func (s *store) AdvanceVersion(version uint64) {
+	s.mx.Lock()
+	defer s.unlock()
+
+	s.version = max(s.version, version)
+}
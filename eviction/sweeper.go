@@ -0,0 +1,25 @@
+package eviction
+
+import (
+	"context"
+	"time"
+)
+
+// Sweep periodically removes expired keys from the cache, until the
+// context is done.
+//
+// expired keys are already treated as missing on read, sweeping is needed
+// to reclaim the memory of keys that are never read again.
+func Sweep(ctx context.Context, algo Algorithm, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			algo.DeleteExpired()
+		}
+	}
+}
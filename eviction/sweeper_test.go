@@ -0,0 +1,33 @@
+package eviction
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSweep(t *testing.T) {
+	algo := NewLRU(10)
+	algo.Put("foo", []byte("bar"), time.Millisecond)
+	algo.Put("bar", []byte("baz"), 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		Sweep(ctx, algo, time.Millisecond)
+	}()
+
+	require.Eventually(t, func() bool {
+		l, _ := algo.(*lru)
+		l.mx.RLock()
+		defer l.mx.RUnlock()
+
+		return l.size == 1
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,124 @@
+package eviction
+
+const (
+	// windowPercent is the share of the capacity, given to the admission
+	// window, the rest is the main cache.
+	windowPercent = 1
+
+	// protectedPercent is the share of the main cache, given to the
+	// protected segment.
+	protectedPercent = 80
+)
+
+// tinyLFU is the W-TinyLFU policy.
+//
+//   - New keys get into the small window, which is managed by LRU, so the
+//     bursts of new keys don't pollute the main cache.
+//   - Keys, evicted from the window, are the candidates for the main cache,
+//     candidate replaces the victim of the main cache only if it's used
+//     more frequently, according to the count-min sketch.
+//   - Main cache is the segmented LRU: keys get into probation and are
+//     promoted to protected on the next access.
+type tinyLFU struct {
+	*store
+	window, probation, protected list
+	windowCap, protectedCap      int
+	sketch                       *sketch
+}
+
+func NewTinyLFU(capacity int, opts ...Option) Algorithm {
+	windowCap := max(1, capacity*windowPercent/100)
+
+	t := &tinyLFU{
+		window:       newList(),
+		probation:    newList(),
+		protected:    newList(),
+		windowCap:    windowCap,
+		protectedCap: (capacity - windowCap) * protectedPercent / 100,
+		sketch:       newSketch(capacity),
+	}
+
+	t.store = newStore(capacity, t, opts)
+	return t
+}
+
+func (t *tinyLFU) added(node *Node) {
+	t.sketch.increment(node.key)
+	t.window.pushFront(node)
+
+	// the cache isn't full yet, so the window overflow goes to the main
+	// cache without the admission.
+	if t.window.len > t.windowCap {
+		candidate := t.window.back()
+		t.window.unlink(candidate)
+		t.probation.pushFront(candidate)
+	}
+}
+
+func (t *tinyLFU) accessed(node *Node) {
+	t.sketch.increment(node.key)
+
+	switch node.owner {
+	case &t.window, &t.protected:
+		node.owner.moveToFront(node)
+	case &t.probation:
+		t.probation.unlink(node)
+		t.protected.pushFront(node)
+
+		if t.protected.len > t.protectedCap {
+			demoted := t.protected.back()
+			t.protected.unlink(demoted)
+			t.probation.pushFront(demoted)
+		}
+	}
+}
+
+func (t *tinyLFU) removed(node *Node) {
+	node.owner.unlink(node)
+}
+
+// mainVictim returns the least recently used key of the main cache,
+// probation keys are evicted first.
+func (t *tinyLFU) mainVictim(key string) *Node {
+	if node := t.probation.backExcept(key); node != nil {
+		return node
+	}
+
+	return t.protected.backExcept(key)
+}
+
+// victim makes the room for the new key in the window, when the window
+// is full, its oldest key competes with the victim of the main cache,
+// the loser is evicted.
+func (t *tinyLFU) victim(key string) *Node {
+	victim := t.mainVictim(key)
+	if victim != nil && t.window.len < t.windowCap {
+		return victim
+	}
+
+	candidate := t.window.backExcept(key)
+	if candidate == nil {
+		return victim
+	}
+
+	if victim == nil || t.sketch.estimate(candidate.key) <= t.sketch.estimate(victim.key) {
+		return candidate
+	}
+
+	t.window.unlink(candidate)
+	t.probation.pushFront(candidate)
+	return victim
+}
+
+func (t *tinyLFU) reset() {
+	t.window.reset()
+	t.probation.reset()
+	t.protected.reset()
+	t.sketch.reset()
+}
+
+func (t *tinyLFU) walk(fn func(node *Node)) {
+	t.probation.walkBack(fn)
+	t.protected.walkBack(fn)
+	t.window.walkBack(fn)
+}
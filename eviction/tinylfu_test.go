@@ -0,0 +1,316 @@
+package eviction
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type tinyLFULists struct {
+	window, probation, protected []string
+}
+
+func tinyLFUOrder(t *tinyLFU) tinyLFULists {
+	return tinyLFULists{
+		window:    listKeys(&t.window),
+		probation: listKeys(&t.probation),
+		protected: listKeys(&t.protected),
+	}
+}
+
+func TestTinyLFU_Get(t *testing.T) {
+	testCases := []struct {
+		name           string
+		cap            int
+		operate        func(t *testing.T, tlfu *tinyLFU)
+		verifyInternal func(t *testing.T, tlfu *tinyLFU)
+	}{
+		{
+			name: "get from empty tinylfu",
+			cap:  10,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				_, _, ok := tlfu.Get("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 0, tlfu.size)
+				require.Equal(t, tinyLFULists{window: []string{}, probation: []string{}, protected: []string{}}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "window overflow goes to probation",
+			cap:  10,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				tlfu.Put("foo", []byte("bar"), 0)
+				tlfu.Put("bar", []byte("baz"), 0)
+				tlfu.Put("baz", []byte("qux"), 0)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 3, tlfu.size)
+				require.Equal(t, tinyLFULists{
+					window:    []string{"baz"},
+					probation: []string{"bar", "foo"},
+					protected: []string{},
+				}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "get promotes to protected",
+			cap:  3,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				tlfu.Put("foo", []byte("bar"), 0)
+				tlfu.Put("bar", []byte("baz"), 0)
+				tlfu.Put("baz", []byte("qux"), 0)
+
+				val, _, ok := tlfu.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("bar"), val)
+				require.Equal(t, []string{"foo"}, listKeys(&tlfu.protected))
+
+				tlfu.Get("bar")
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 3, tlfu.size)
+				require.Equal(t, tinyLFULists{
+					window:    []string{"baz"},
+					probation: []string{"foo"},
+					protected: []string{"bar"},
+				}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "admission by frequency",
+			cap:  3,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				tlfu.Put("a", []byte("1"), 0)
+				tlfu.Put("b", []byte("2"), 0)
+				tlfu.Put("c", []byte("3"), 0)
+				tlfu.Get("a")
+				tlfu.Get("a")
+				tlfu.Get("b")
+
+				tlfu.Put("d", []byte("4"), 0)
+				_, _, ok := tlfu.Get("c")
+				require.False(t, ok)
+				require.Equal(t, tinyLFULists{
+					window:    []string{"d"},
+					probation: []string{"a"},
+					protected: []string{"b"},
+				}, tinyLFUOrder(tlfu))
+
+				tlfu.Get("d")
+				tlfu.Get("d")
+				tlfu.Get("d")
+				tlfu.Put("e", []byte("5"), 0)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 3, tlfu.size)
+				require.Equal(t, uint64(2), tlfu.evictions)
+				require.Equal(t, tinyLFULists{
+					window:    []string{"e"},
+					probation: []string{"d"},
+					protected: []string{"b"},
+				}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "hot keys survive the scan",
+			cap:  100,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				for round := 0; round < 5; round++ {
+					for i := 0; i < 20; i++ {
+						key := "hot:" + strconv.Itoa(i)
+						if _, _, ok := tlfu.Get(key); !ok {
+							tlfu.Put(key, []byte(key), 0)
+						}
+					}
+				}
+
+				for i := 0; i < 1000; i++ {
+					key := "cold:" + strconv.Itoa(i)
+					tlfu.Put(key, []byte(key), 0)
+				}
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 100, tlfu.size)
+				for i := 0; i < 20; i++ {
+					_, ok := tlfu.cache["hot:"+strconv.Itoa(i)]
+					require.True(t, ok)
+				}
+			},
+		},
+		{
+			name: "single capacity",
+			cap:  1,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				tlfu.Put("foo", []byte("bar"), 0)
+				tlfu.Get("foo")
+				tlfu.Put("bar", []byte("baz"), 0)
+				tlfu.Put("baz", []byte("qux"), 0)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 1, tlfu.size)
+				require.Equal(t, tinyLFULists{
+					window:    []string{"baz"},
+					probation: []string{},
+					protected: []string{},
+				}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "delete",
+			cap:  10,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				tlfu.Put("foo", []byte("bar"), 0)
+				tlfu.Put("bar", []byte("baz"), 0)
+				tlfu.Get("foo")
+				require.True(t, tlfu.Delete("foo"))
+				require.False(t, tlfu.Delete("foo"))
+
+				_, _, ok := tlfu.Get("foo")
+				require.False(t, ok)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 1, tlfu.size)
+				require.Equal(t, uint64(6), tlfu.bytes)
+				require.Equal(t, tinyLFULists{
+					window:    []string{"bar"},
+					probation: []string{},
+					protected: []string{},
+				}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "expired on get",
+			cap:  10,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				now := time.Unix(0, 0)
+				tlfu.now = func() time.Time { return now }
+
+				tlfu.Put("foo", []byte("bar"), time.Second)
+				tlfu.Put("bar", []byte("baz"), 0)
+				tlfu.Get("foo")
+
+				now = now.Add(time.Second)
+				_, _, ok := tlfu.Get("foo")
+				require.False(t, ok)
+				require.Equal(t, 0, tlfu.DeleteExpired())
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 1, tlfu.size)
+				require.Equal(t, uint64(1), tlfu.expired)
+				require.Equal(t, 0, tlfu.probation.len+tlfu.protected.len)
+			},
+		},
+		{
+			name: "growing update doesn't evict the key",
+			cap:  10,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				tlfu.maxBytes = 2*entryOverhead + 100
+
+				tlfu.Put("a", []byte("x"), 0)
+				tlfu.Put("b", []byte("y"), 0)
+				for i := 0; i < 5; i++ {
+					tlfu.Get("b")
+				}
+
+				require.Equal(t, uint64(3), tlfu.Put("a", bytes.Repeat([]byte("x"), 150), 0))
+
+				val, _, ok := tlfu.Get("a")
+				require.True(t, ok)
+				require.Len(t, val, 150)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 1, tlfu.size)
+				require.Equal(t, uint64(1), tlfu.evictions)
+				require.Equal(t, tinyLFULists{
+					window:    []string{},
+					probation: []string{},
+					protected: []string{"a"},
+				}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "listener notified about removals",
+			cap:  2,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				var removed []removal
+				tlfu.notifier.listeners = append(tlfu.notifier.listeners, func(key string, val []byte, reason Reason) {
+					removed = append(removed, removal{key: key, val: val, reason: reason})
+				})
+
+				tlfu.Put("foo", []byte("bar"), 0)
+				tlfu.Put("bar", []byte("baz"), 0)
+				tlfu.Get("foo")
+				tlfu.Put("baz", []byte("qux"), 0)
+
+				require.Equal(t, []removal{
+					{key: "bar", val: []byte("baz"), reason: ReasonCapacity},
+				}, removed)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 2, tlfu.size)
+				require.Empty(t, tlfu.notifier.pending)
+			},
+		},
+		{
+			name: "flush all",
+			cap:  10,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				tlfu.Put("foo", []byte("bar"), time.Hour)
+				tlfu.Put("bar", []byte("baz"), 0)
+				tlfu.Get("foo")
+				require.Equal(t, 2, tlfu.Flush(""))
+
+				tlfu.Put("baz", []byte("qux"), 0)
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, 1, tlfu.size)
+				require.Equal(t, uint8(0), tlfu.sketch.estimate("foo"))
+				require.Equal(t, tinyLFULists{
+					window:    []string{"baz"},
+					probation: []string{},
+					protected: []string{},
+				}, tinyLFUOrder(tlfu))
+			},
+		},
+		{
+			name: "in goroutines",
+			cap:  100,
+			operate: func(t *testing.T, tlfu *tinyLFU) {
+				var wg sync.WaitGroup
+				for i := 0; i < tlfu.cap*3; i++ {
+					wg.Add(1)
+
+					go func(i int) {
+						defer wg.Done()
+
+						key := strconv.Itoa(i % (tlfu.cap * 2))
+						tlfu.Put(key, []byte(key), 0)
+						tlfu.Get(key)
+					}(i)
+				}
+
+				wg.Wait()
+			},
+			verifyInternal: func(t *testing.T, tlfu *tinyLFU) {
+				require.Equal(t, tlfu.cap, tlfu.size)
+				require.Equal(t, tlfu.size, tlfu.window.len+tlfu.probation.len+tlfu.protected.len)
+				require.LessOrEqual(t, tlfu.protected.len, tlfu.protectedCap)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			algo := NewTinyLFU(tc.cap)
+
+			ep, _ := algo.(*tinyLFU)
+			tc.operate(t, ep)
+			tc.verifyInternal(t, ep)
+		})
+	}
+}
@@ -64,6 +64,12 @@ func WithInitialState(path string) NodesConfigOption {
 			return fmt.Errorf("failed to setup nodes from config: %w", err)
 		}
 
+		for _, n := range cfg.Nodes {
+			if e := n.ToShard().Validate(); e != nil {
+				return fmt.Errorf("failed to setup nodes from config: %w", e)
+			}
+		}
+
 		c.Nodes = cfg.Nodes
 		c.keys = c.Nodes.NodeIDs()
 		for _, n := range c.Nodes {
@@ -122,6 +128,8 @@ func (c *NodesConfig) syncStates(desired []*api.Node) (bool, error) {
 				c.setupWithObservability(errCh, d)
 			case nodeStateRemoved:
 				c.teardownWithObservability(errCh, d)
+			case nodeStateUpdated:
+				c.updateWithObservability(errCh, d)
 			case nodeStateSynced:
 				zap.S().Infof("node %s is synced", d.id)
 			default:
@@ -149,12 +157,18 @@ func (c *NodesConfig) diff(desired []*api.Node) map[string]*nodeDiff {
 	c.mx.RUnlock()
 
 	for _, n := range desired {
-		if _, ok := clientState[n.Id]; !ok {
+		current, ok := clientState[n.Id]
+		if !ok {
 			clientState[n.Id] = newNodeDiffFromApiNode(n, nodeStateAdded)
 			continue
 		}
 
-		clientState[n.Id].state = nodeStateSynced
+		d := newNodeDiffFromApiNode(n, nodeStateSynced)
+		if d.host != current.host || d.port != current.port || d.weight != current.weight {
+			d.state = nodeStateUpdated
+		}
+
+		clientState[n.Id] = d
 	}
 
 	return clientState
@@ -169,6 +183,10 @@ func (c *NodesConfig) setupNode(n *nodeDiff) error {
 	}
 
 	node := n.toNode()
+	if e := node.ToShard().Validate(); e != nil {
+		return e
+	}
+
 	if e := node.RefreshClient(context.Background()); e != nil {
 		return fmt.Errorf("failed to refresh client: %w", e)
 	}
@@ -190,6 +208,51 @@ func (c *NodesConfig) setupWithObservability(
 	errs <- nil
 }
 
+// updateNode replaces the node with the same ID, the connection is kept,
+// when only the weight is changed.
+func (c *NodesConfig) updateNode(n *nodeDiff) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	current, ok := c.Nodes[n.id]
+	if !ok {
+		return fmt.Errorf("node %s doesn't exist", n.id)
+	}
+
+	node := n.toNode()
+	if e := node.ToShard().Validate(); e != nil {
+		return e
+	}
+
+	if node.connString() == current.connString() {
+		node.cc, node.gclient = current.cc, current.gclient
+	} else {
+		if e := node.RefreshClient(context.Background()); e != nil {
+			return fmt.Errorf("failed to refresh client: %w", e)
+		}
+
+		if e := current.Close(); e != nil {
+			// ignoring the error, system state need to be updated any way
+			zap.S().Errorf("failed to close node %s: %v", n.id, e)
+		}
+	}
+
+	c.Nodes[n.id] = node
+	return nil
+}
+
+func (c *NodesConfig) updateWithObservability(
+	errs chan<- error, d *nodeDiff,
+) {
+	zap.S().Infof("updating node %s", d.id)
+	if err := c.updateNode(d); err != nil {
+		errs <- fmt.Errorf("failed to update node: %w", err)
+		return
+	}
+
+	errs <- nil
+}
+
 func (c *NodesConfig) teardownNode(n *nodeDiff) error {
 	c.mx.Lock()
 	defer c.mx.Unlock()
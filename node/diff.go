@@ -3,17 +3,19 @@ package node
 import "github.com/fadyat/speedy/api"
 
 type nodeDiff struct {
-	id    string
-	host  string
-	port  int
-	state nodeState
+	id     string
+	host   string
+	port   int
+	weight int
+	state  nodeState
 }
 
 func (d *nodeDiff) toNode() *Node {
 	return &Node{
-		ID:   d.id,
-		Host: d.host,
-		Port: d.port,
+		ID:     d.id,
+		Host:   d.host,
+		Port:   d.port,
+		Weight: d.weight,
 	}
 }
 
@@ -22,10 +24,11 @@ func newNodeDiffFromApiNode(
 	state nodeState,
 ) *nodeDiff {
 	return &nodeDiff{
-		id:    n.Id,
-		host:  n.Host,
-		port:  int(n.Port),
-		state: state,
+		id:     n.Id,
+		host:   n.Host,
+		port:   int(n.Port),
+		weight: int(n.Weight),
+		state:  state,
 	}
 }
 
@@ -34,10 +37,11 @@ func newNodeDiffFromNode(
 	state nodeState,
 ) *nodeDiff {
 	return &nodeDiff{
-		id:    n.ID,
-		host:  n.Host,
-		port:  n.Port,
-		state: state,
+		id:     n.ID,
+		host:   n.Host,
+		port:   n.Port,
+		weight: n.Weight,
+		state:  state,
 	}
 }
 
@@ -52,4 +56,8 @@ const (
 
 	// nodeStateRemoved client needs to close the connection to the node.
 	nodeStateRemoved
+
+	// nodeStateUpdated node has the new address or weight, client needs
+	// to replace it.
+	nodeStateUpdated
 )
@@ -27,9 +27,10 @@ func (n Nodes) NodesApiStyle() []*api.Node {
 	var nodes = make([]*api.Node, 0, len(n))
 	for _, v := range n {
 		nodes = append(nodes, &api.Node{
-			Id:   v.ID,
-			Host: v.Host,
-			Port: uint32(v.Port),
+			Id:     v.ID,
+			Host:   v.Host,
+			Port:   uint32(v.Port),
+			Weight: uint32(v.Weight),
 		})
 	}
 
@@ -41,6 +42,12 @@ type Node struct {
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
 
+	// Weight is the relative share of keys owned by the node, it's used
+	// by the sharding, nodes of different sizes should have different
+	// weights, zero weight is treated as 1, the largest one is
+	// sharding.MaxWeight.
+	Weight int `yaml:"weight"`
+
 	// storing the gRPC client and the connection to the node, because
 	// it's so expensive to create a new client every time we want to
 	// send a request to the node.
@@ -108,8 +115,9 @@ func (n *Node) Request() api.CacheServiceClient {
 
 func (n *Node) ToShard() *sharding.Shard {
 	return &sharding.Shard{
-		ID:   n.ID,
-		Host: n.Host,
-		Port: n.Port,
+		ID:     n.ID,
+		Host:   n.Host,
+		Port:   n.Port,
+		Weight: n.Weight,
 	}
 }
@@ -0,0 +1,224 @@
+package oplog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/fadyat/speedy/eviction"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+type Kind uint8
+
+const (
+	KindPut Kind = iota + 1
+	KindDelete
+	KindFlush
+)
+
+// Op is the single write to the cache.
+//
+// every operation keeps its result, puts keep the version and the absolute
+// deadline, deletes and flushes keep the version counter of the cache, so
+// replaying them is idempotent. The compaction may fold the old log into
+// the snapshot right before the crash, so the old log is replayed again.
+type Op struct {
+	Kind Kind
+
+	// Key is the prefix for flushes, empty prefix flushes the whole cache.
+	Key       string
+	Value     []byte
+	Version   uint64
+	ExpiresAt time.Time
+}
+
+func deadline(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(ttl)
+}
+
+func Put(key string, val []byte, version uint64, ttl time.Duration) Op {
+	return Op{Kind: KindPut, Key: key, Value: val, Version: version, ExpiresAt: deadline(ttl)}
+}
+
+// Incr is logged as the put of the result, the key keeps its deadline,
+// so it's taken from the cache, the operation must be created under the
+// same lock as the increment, see Log.Write.
+func Incr(cache eviction.Algorithm, key string) Op {
+	r, ok := cache.Peek(key)
+	if !ok {
+		// the key is expired right after the increment.
+		return Delete(key, cache.Version())
+	}
+
+	return Op{Kind: KindPut, Key: key, Value: r.Value, Version: r.Version, ExpiresAt: r.ExpiresAt}
+}
+
+// Delete keeps the version counter, it's not moved by the delete itself,
+// but the versions of the deleted keys must not be reused after the replay.
+func Delete(key string, version uint64) Op {
+	return Op{Kind: KindDelete, Key: key, Version: version}
+}
+
+func Flush(prefix string, version uint64) Op {
+	return Op{Kind: KindFlush, Key: prefix, Version: version}
+}
+
+// apply replays the operation on the cache.
+func (op Op) apply(algo eviction.Algorithm, now time.Time) {
+	expired := !op.ExpiresAt.IsZero() && !now.Before(op.ExpiresAt)
+
+	switch op.Kind {
+	case KindPut:
+		if expired {
+			algo.Delete(op.Key)
+			break
+		}
+
+		algo.Restore([]eviction.Record{{
+			Key: op.Key, Value: op.Value, Version: op.Version, ExpiresAt: op.ExpiresAt,
+		}})
+	case KindDelete:
+		algo.Delete(op.Key)
+	case KindFlush:
+		algo.Flush(op.Key)
+	}
+
+	algo.AdvanceVersion(op.Version)
+}
+
+// entryHeaderSize is the uint32 payload length followed by its uint32 crc32.
+const entryHeaderSize = 8
+
+var (
+	ErrCorrupted = errors.New("operation log is corrupted")
+
+	// errTorn is returned for the entry, which is cut by the end of the file,
+	// it's left by the crash in the middle of the write.
+	errTorn = errors.New("torn entry")
+)
+
+func (op Op) appendTo(dst []byte) []byte {
+	payload := []byte{byte(op.Kind)}
+	payload = binary.AppendUvarint(payload, uint64(len(op.Key)))
+	payload = append(payload, op.Key...)
+
+	switch op.Kind {
+	case KindPut:
+		payload = binary.AppendUvarint(payload, uint64(len(op.Value)))
+		payload = append(payload, op.Value...)
+		payload = binary.AppendUvarint(payload, op.Version)
+		payload = binary.AppendVarint(payload, unixNano(op.ExpiresAt))
+	case KindDelete, KindFlush:
+		payload = binary.AppendUvarint(payload, op.Version)
+	}
+
+	dst = binary.BigEndian.AppendUint32(dst, uint32(len(payload)))
+	dst = binary.BigEndian.AppendUint32(dst, crc32.ChecksumIEEE(payload))
+	return append(dst, payload...)
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.UnixNano()
+}
+
+// decodeOp reads the single entry from the start of the data and returns
+// its size.
+func decodeOp(data []byte) (Op, int, error) {
+	if len(data) < entryHeaderSize {
+		return Op{}, 0, errTorn
+	}
+
+	size := int(binary.BigEndian.Uint32(data))
+	if len(data)-entryHeaderSize < size {
+		return Op{}, 0, errTorn
+	}
+
+	payload := data[entryHeaderSize : entryHeaderSize+size]
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(data[4:]) {
+		return Op{}, 0, fmt.Errorf("%w: checksum mismatch", ErrCorrupted)
+	}
+
+	op, err := decodePayload(payload)
+	if err != nil {
+		return Op{}, 0, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+
+	return op, entryHeaderSize + size, nil
+}
+
+func decodePayload(payload []byte) (Op, error) {
+	r := bytes.NewReader(payload)
+
+	kind, err := r.ReadByte()
+	if err != nil {
+		return Op{}, err
+	}
+
+	readBytes := func() ([]byte, error) {
+		n, e := binary.ReadUvarint(r)
+		if e != nil {
+			return nil, e
+		}
+
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		b := make([]byte, n)
+		_, e = io.ReadFull(r, b)
+		return b, e
+	}
+
+	readDeadline := func() (time.Time, error) {
+		at, e := binary.ReadVarint(r)
+		if e != nil || at == 0 {
+			return time.Time{}, e
+		}
+
+		return time.Unix(0, at), nil
+	}
+
+	key, err := readBytes()
+	if err != nil {
+		return Op{}, err
+	}
+
+	op := Op{Kind: Kind(kind), Key: string(key)}
+	switch op.Kind {
+	case KindPut:
+		if op.Value, err = readBytes(); err != nil {
+			return Op{}, err
+		}
+
+		if op.Version, err = binary.ReadUvarint(r); err != nil {
+			return Op{}, err
+		}
+
+		op.ExpiresAt, err = readDeadline()
+	case KindDelete, KindFlush:
+		op.Version, err = binary.ReadUvarint(r)
+	default:
+		return Op{}, fmt.Errorf("unknown operation: %d", kind)
+	}
+
+	if err != nil {
+		return Op{}, err
+	}
+
+	if r.Len() != 0 {
+		return Op{}, errors.New("trailing data")
+	}
+
+	return op, nil
+}
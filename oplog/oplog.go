@@ -0,0 +1,407 @@
+package oplog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/fadyat/speedy/eviction"
+	"github.com/fadyat/speedy/snapshot"
+	"go.uber.org/zap"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyncPolicy defines, when the log is flushed to the disk.
+type SyncPolicy string
+
+const (
+
+	// SyncAlways syncs the log after every write, before the response.
+	SyncAlways SyncPolicy = "always"
+
+	// SyncEverySecond syncs the log in the background, the crash loses
+	// up to a second of writes.
+	SyncEverySecond SyncPolicy = "everysec"
+
+	// SyncNever leaves the flushing to the OS.
+	SyncNever SyncPolicy = "never"
+)
+
+const (
+	defaultRewriteSize = 64 << 20
+	syncPeriod         = time.Second
+)
+
+var (
+	ErrUnknownSyncPolicy = errors.New("unknown sync policy")
+)
+
+func (p SyncPolicy) Validate() error {
+	switch p {
+	case SyncAlways, SyncEverySecond, SyncNever:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrUnknownSyncPolicy, p)
+}
+
+type Option func(*Log)
+
+func WithSyncPolicy(p SyncPolicy) Option {
+	return func(l *Log) {
+		l.policy = p
+	}
+}
+
+// WithRewriteSize sets the size of the log, after which Run compacts it
+// into the snapshot.
+func WithRewriteSize(size int64) Option {
+	return func(l *Log) {
+		l.rewriteSize = size
+	}
+}
+
+// Log is the append-only log of the cache writes made since the last
+// snapshot, so the restart loses only not synced writes.
+//
+// the cache is changed and the operation is appended under the same lock,
+// so the order of the log is the order of the cache and the compaction
+// splits the log exactly at the snapshot.
+type Log struct {
+	mx   sync.Mutex
+	file *os.File
+	size int64
+
+	// dirty is set, when there are writes, which aren't synced yet.
+	dirty bool
+	buf   []byte
+
+	// compactMx serializes compactions, the background and the final one.
+	compactMx  sync.Mutex
+	compacting atomic.Bool
+
+	path         string
+	snapshotPath string
+	cache        eviction.Algorithm
+	policy       SyncPolicy
+	rewriteSize  int64
+}
+
+// Open opens the log at the path for appending, the log is compacted
+// into the snapshot at the snapshotPath.
+//
+// the log must be replayed before, see Replay.
+func Open(path, snapshotPath string, cache eviction.Algorithm, opts ...Option) (*Log, error) {
+	l := &Log{
+		path:         path,
+		snapshotPath: snapshotPath,
+		cache:        cache,
+		policy:       SyncEverySecond,
+		rewriteSize:  defaultRewriteSize,
+	}
+
+	for _, o := range opts {
+		o(l)
+	}
+
+	if err := l.policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+func (l *Log) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open operation log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat operation log: %w", err)
+	}
+
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Write calls fn and appends the returned operations to the log, fn
+// changes the cache and returns nothing, when the cache isn't changed.
+//
+// with SyncAlways the operations are synced before the return, errors
+// mean that the cache is changed, but the change isn't durable.
+func (l *Log) Write(fn func() []Op) error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	ops := fn()
+	if len(ops) == 0 {
+		return nil
+	}
+
+	l.buf = l.buf[:0]
+	for _, op := range ops {
+		l.buf = op.appendTo(l.buf)
+	}
+
+	// the partially written entry is cut, otherwise the next entries are
+	// appended after it and the replay fails in the middle of the log.
+	if n, err := l.file.Write(l.buf); err != nil {
+		if n > 0 {
+			if e := l.file.Truncate(l.size); e != nil {
+				err = errors.Join(err, fmt.Errorf("failed to truncate operation log: %w", e))
+			}
+		}
+
+		return fmt.Errorf("failed to append operation: %w", err)
+	}
+
+	l.size += int64(len(l.buf))
+
+	if l.policy != SyncAlways {
+		l.dirty = true
+		return nil
+	}
+
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync operation log: %w", err)
+	}
+
+	return nil
+}
+
+// Sync flushes the appended operations to the disk.
+func (l *Log) Sync() error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return l.syncUnsafe()
+}
+
+func (l *Log) syncUnsafe() error {
+	if !l.dirty {
+		return nil
+	}
+
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync operation log: %w", err)
+	}
+
+	l.dirty = false
+	return nil
+}
+
+// Size returns the size of the log in bytes.
+func (l *Log) Size() int64 {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return l.size
+}
+
+func oldPath(path string) string {
+	return path + ".old"
+}
+
+// rotate moves the written operations to the old log and starts the empty
+// one, the old log is kept until the snapshot is written.
+//
+// if the old log is left by the failed compaction, the operations are
+// appended to it, so it has all the writes since the last snapshot.
+func (l *Log) rotate() error {
+	l.dirty = true
+	if err := l.syncUnsafe(); err != nil {
+		return err
+	}
+
+	old := oldPath(l.path)
+	if _, err := os.Stat(old); errors.Is(err, fs.ErrNotExist) {
+		if err = os.Rename(l.path, old); err != nil {
+			return fmt.Errorf("failed to rotate operation log: %w", err)
+		}
+
+		_ = l.file.Close()
+		return l.open()
+	}
+
+	if err := appendFile(old, l.path); err != nil {
+		return fmt.Errorf("failed to rotate operation log: %w", err)
+	}
+
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate operation log: %w", err)
+	}
+
+	l.size = 0
+	return nil
+}
+
+func appendFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// Compact folds the log into the fresh snapshot of the cache and returns
+// the number of saved keys.
+//
+// writes are blocked only while the cache is copied, the snapshot is
+// written in the background.
+func (l *Log) Compact() (int, error) {
+	l.compactMx.Lock()
+	defer l.compactMx.Unlock()
+
+	l.mx.Lock()
+	records, version := l.cache.Snapshot(), l.cache.Version()
+	err := l.rotate()
+	l.mx.Unlock()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if err = snapshot.Write(l.snapshotPath, version, records); err != nil {
+		return 0, err
+	}
+
+	if err = os.Remove(oldPath(l.path)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("failed to remove old operation log: %w", err)
+	}
+
+	return len(records), nil
+}
+
+// Run syncs the log every second with SyncEverySecond and compacts it,
+// when it grows over the rewrite size, until the context is done.
+func (l *Log) Run(ctx context.Context) {
+	ticker := time.NewTicker(syncPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if l.policy == SyncEverySecond {
+				if err := l.Sync(); err != nil {
+					zap.L().Error("failed to sync operation log", zap.Error(err))
+				}
+			}
+
+			if l.Size() >= l.rewriteSize && l.compacting.CompareAndSwap(false, true) {
+				go l.compact()
+			}
+		}
+	}
+}
+
+func (l *Log) compact() {
+	defer l.compacting.Store(false)
+
+	saved, err := l.Compact()
+	if err != nil {
+		zap.L().Error("failed to compact operation log", zap.String("path", l.path), zap.Error(err))
+		return
+	}
+
+	zap.L().Info("operation log compacted", zap.String("path", l.path), zap.Int("keys", saved))
+}
+
+// Close syncs and closes the log.
+func (l *Log) Close() error {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.dirty = true
+	if err := l.syncUnsafe(); err != nil {
+		_ = l.file.Close()
+		return err
+	}
+
+	return l.file.Close()
+}
+
+// Replay applies the operations from the log at the path to the cache,
+// it's called after the snapshot is loaded, and returns the number of
+// replayed operations, missing log is treated as the empty one.
+//
+// the old log, left by the failed compaction, is replayed first. The entry
+// cut by the crash at the end of the log is dropped, the log is truncated
+// before it, so new writes are appended after the last complete entry.
+func Replay(path string, cache eviction.Algorithm) (int, error) {
+	var replayed int
+	for _, p := range []string{oldPath(path), path} {
+		n, err := replay(p, cache)
+		replayed += n
+		if err != nil {
+			return replayed, err
+		}
+	}
+
+	return replayed, nil
+}
+
+func replay(path string, cache eviction.Algorithm) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		now      = time.Now()
+		offset   int
+		replayed int
+	)
+
+	for offset < len(data) {
+		op, n, e := decodeOp(data[offset:])
+		if errors.Is(e, errTorn) {
+			zap.L().Warn("operation log ends with the torn entry, truncating",
+				zap.String("path", path), zap.Int("offset", offset))
+			return replayed, os.Truncate(path, int64(offset))
+		}
+
+		if e != nil {
+			return replayed, fmt.Errorf("%s at offset %d: %w", path, offset, e)
+		}
+
+		op.apply(cache, now)
+		offset += n
+		replayed++
+	}
+
+	return replayed, nil
+}
@@ -0,0 +1,336 @@
+package oplog
+
+import (
+	"context"
+	"github.com/fadyat/speedy/eviction"
+	"github.com/fadyat/speedy/snapshot"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func put(l *Log, algo eviction.Algorithm, key, val string, ttl time.Duration) error {
+	return l.Write(func() []Op {
+		version := algo.Put(key, []byte(val), ttl)
+		return []Op{Put(key, []byte(val), version, ttl)}
+	})
+}
+
+func incr(l *Log, algo eviction.Algorithm, key string, delta int64) error {
+	return l.Write(func() []Op {
+		if _, _, err := algo.Incr(key, delta, 0); err != nil {
+			return nil
+		}
+
+		return []Op{Incr(algo, key)}
+	})
+}
+
+func TestSyncPolicy_Validate(t *testing.T) {
+	for _, p := range []SyncPolicy{SyncAlways, SyncEverySecond, SyncNever} {
+		require.NoError(t, p.Validate())
+	}
+
+	require.ErrorIs(t, SyncPolicy("sometimes").Validate(), ErrUnknownSyncPolicy)
+}
+
+func TestLog(t *testing.T) {
+	testCases := []struct {
+		name    string
+		operate func(t *testing.T, dir string)
+	}{
+		{
+			name: "replay restores all operations",
+			operate: func(t *testing.T, dir string) {
+				for _, p := range []SyncPolicy{SyncAlways, SyncEverySecond, SyncNever} {
+					path := filepath.Join(dir, string(p)+".log")
+					source := eviction.NewLRU(10)
+
+					l, err := Open(path, filepath.Join(dir, "cache.snapshot"), source, WithSyncPolicy(p))
+					require.NoError(t, err)
+
+					require.NoError(t, put(l, source, "foo", "bar", 0))
+					require.NoError(t, put(l, source, "bar", "baz", 0))
+					require.NoError(t, put(l, source, "user:1", "alice", 0))
+					require.NoError(t, put(l, source, "user:2", "bob", 0))
+					require.NoError(t, incr(l, source, "counter", 5))
+					require.NoError(t, incr(l, source, "counter", -2))
+					require.NoError(t, l.Write(func() []Op {
+						require.True(t, source.Delete("bar"))
+						return []Op{Delete("bar", source.Version())}
+					}))
+					require.NoError(t, l.Write(func() []Op {
+						require.Equal(t, 2, source.Flush("user:"))
+						return []Op{Flush("user:", source.Version())}
+					}))
+					require.NoError(t, l.Write(func() []Op { return nil }))
+					require.NoError(t, l.Close())
+
+					restored := eviction.NewLRU(10)
+					replayed, err := Replay(path, restored)
+					require.NoError(t, err)
+					require.Equal(t, 8, replayed)
+					require.Equal(t, source.Snapshot(), restored.Snapshot(), p)
+					require.Equal(t, source.Version(), restored.Version(), p)
+				}
+			},
+		},
+		{
+			name: "expired puts are deleted on replay",
+			operate: func(t *testing.T, dir string) {
+				path := filepath.Join(dir, "cache.log")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, filepath.Join(dir, "cache.snapshot"), source)
+				require.NoError(t, err)
+
+				require.NoError(t, put(l, source, "foo", "bar", 0))
+				require.NoError(t, put(l, source, "foo", "baz", 10*time.Millisecond))
+				require.NoError(t, l.Close())
+
+				time.Sleep(20 * time.Millisecond)
+
+				restored := eviction.NewLRU(10)
+				_, err = Replay(path, restored)
+				require.NoError(t, err)
+
+				_, _, ok := restored.Get("foo")
+				require.False(t, ok)
+			},
+		},
+		{
+			name: "increments keep the deadline",
+			operate: func(t *testing.T, dir string) {
+				path := filepath.Join(dir, "cache.log")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, filepath.Join(dir, "cache.snapshot"), source)
+				require.NoError(t, err)
+
+				require.NoError(t, put(l, source, "n", "1", 10*time.Millisecond))
+				require.NoError(t, incr(l, source, "n", 1))
+				require.NoError(t, l.Close())
+
+				time.Sleep(20 * time.Millisecond)
+
+				restored := eviction.NewLRU(10)
+				_, err = Replay(path, restored)
+				require.NoError(t, err)
+
+				_, _, ok := restored.Get("n")
+				require.False(t, ok)
+				require.Equal(t, uint64(2), restored.Version())
+			},
+		},
+		{
+			name: "missing log is empty",
+			operate: func(t *testing.T, dir string) {
+				replayed, err := Replay(filepath.Join(dir, "cache.log"), eviction.NewLRU(10))
+				require.NoError(t, err)
+				require.Equal(t, 0, replayed)
+			},
+		},
+		{
+			name: "torn entry is truncated",
+			operate: func(t *testing.T, dir string) {
+				path := filepath.Join(dir, "cache.log")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, filepath.Join(dir, "cache.snapshot"), source)
+				require.NoError(t, err)
+				require.NoError(t, put(l, source, "foo", "bar", 0))
+				size := l.Size()
+				require.NoError(t, put(l, source, "bar", "baz", 0))
+				require.NoError(t, l.Close())
+
+				// the crash in the middle of the second write.
+				require.NoError(t, os.Truncate(path, size+3))
+
+				restored := eviction.NewLRU(10)
+				replayed, err := Replay(path, restored)
+				require.NoError(t, err)
+				require.Equal(t, 1, replayed)
+
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				require.Equal(t, size, info.Size())
+
+				// new writes are appended after the last complete entry.
+				l, err = Open(path, filepath.Join(dir, "cache.snapshot"), restored)
+				require.NoError(t, err)
+				require.NoError(t, put(l, restored, "baz", "qux", 0))
+				require.NoError(t, l.Close())
+
+				replayed, err = Replay(path, eviction.NewLRU(10))
+				require.NoError(t, err)
+				require.Equal(t, 2, replayed)
+			},
+		},
+		{
+			name: "corrupted entry",
+			operate: func(t *testing.T, dir string) {
+				path := filepath.Join(dir, "cache.log")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, filepath.Join(dir, "cache.snapshot"), source)
+				require.NoError(t, err)
+				require.NoError(t, put(l, source, "foo", "bar", 0))
+				require.NoError(t, put(l, source, "bar", "baz", 0))
+				require.NoError(t, l.Close())
+
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+
+				data[entryHeaderSize+2] ^= 0xff
+				require.NoError(t, os.WriteFile(path, data, 0o600))
+
+				_, err = Replay(path, eviction.NewLRU(10))
+				require.ErrorIs(t, err, ErrCorrupted)
+			},
+		},
+		{
+			name: "compaction folds the log into the snapshot",
+			operate: func(t *testing.T, dir string) {
+				path, snapshotPath := filepath.Join(dir, "cache.log"), filepath.Join(dir, "cache.snapshot")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, snapshotPath, source)
+				require.NoError(t, err)
+
+				for i := 0; i < 5; i++ {
+					require.NoError(t, put(l, source, "foo", strconv.Itoa(i), 0))
+				}
+
+				saved, err := l.Compact()
+				require.NoError(t, err)
+				require.Equal(t, 1, saved)
+				require.Zero(t, l.Size())
+
+				// writes after the compaction are kept in the new log.
+				require.NoError(t, put(l, source, "bar", "baz", 0))
+				require.NoError(t, l.Close())
+
+				entries, err := os.ReadDir(dir)
+				require.NoError(t, err)
+				require.Len(t, entries, 2)
+
+				restored := eviction.NewLRU(10)
+				_, err = snapshot.Load(snapshotPath, restored)
+				require.NoError(t, err)
+
+				replayed, err := Replay(path, restored)
+				require.NoError(t, err)
+				require.Equal(t, 1, replayed)
+				require.Equal(t, source.Snapshot(), restored.Snapshot())
+			},
+		},
+		{
+			name: "crash before the old log is removed",
+			operate: func(t *testing.T, dir string) {
+				path, snapshotPath := filepath.Join(dir, "cache.log"), filepath.Join(dir, "cache.snapshot")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, snapshotPath, source)
+				require.NoError(t, err)
+				require.NoError(t, incr(l, source, "n", 1))
+				require.NoError(t, put(l, source, "foo", "bar", 0))
+				require.NoError(t, l.Write(func() []Op {
+					require.True(t, source.Delete("foo"))
+					return []Op{Delete("foo", source.Version())}
+				}))
+				require.NoError(t, l.Close())
+
+				// the log is rotated and the snapshot is written, but the
+				// old log is still there.
+				require.NoError(t, os.Rename(path, oldPath(path)))
+				_, err = snapshot.Save(snapshotPath, source)
+				require.NoError(t, err)
+
+				restored := eviction.NewLRU(10)
+				_, err = snapshot.Load(snapshotPath, restored)
+				require.NoError(t, err)
+
+				replayed, err := Replay(path, restored)
+				require.NoError(t, err)
+				require.Equal(t, 3, replayed)
+
+				val, _, ok := restored.Get("n")
+				require.True(t, ok)
+				require.Equal(t, []byte("1"), val)
+				require.Equal(t, source.Snapshot(), restored.Snapshot())
+				require.Equal(t, source.Version(), restored.Version())
+			},
+		},
+		{
+			name: "failed compaction keeps the old log",
+			operate: func(t *testing.T, dir string) {
+				path := filepath.Join(dir, "cache.log")
+				snapshotPath := filepath.Join(dir, "missing", "cache.snapshot")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, snapshotPath, source)
+				require.NoError(t, err)
+
+				require.NoError(t, put(l, source, "foo", "bar", 0))
+				_, err = l.Compact()
+				require.Error(t, err)
+
+				require.NoError(t, put(l, source, "bar", "baz", 0))
+				_, err = l.Compact()
+				require.Error(t, err)
+
+				require.NoError(t, put(l, source, "baz", "qux", 0))
+				require.NoError(t, l.Close())
+
+				restored := eviction.NewLRU(10)
+				replayed, err := Replay(path, restored)
+				require.NoError(t, err)
+				require.Equal(t, 3, replayed)
+				require.Equal(t, source.Snapshot(), restored.Snapshot())
+
+				// the next successful compaction removes the old log.
+				require.NoError(t, os.Mkdir(filepath.Dir(snapshotPath), 0o700))
+				l, err = Open(path, snapshotPath, restored)
+				require.NoError(t, err)
+
+				_, err = l.Compact()
+				require.NoError(t, err)
+				require.NoError(t, l.Close())
+
+				_, err = os.Stat(oldPath(path))
+				require.ErrorIs(t, err, os.ErrNotExist)
+			},
+		},
+		{
+			name: "background compaction",
+			operate: func(t *testing.T, dir string) {
+				path, snapshotPath := filepath.Join(dir, "cache.log"), filepath.Join(dir, "cache.snapshot")
+				source := eviction.NewLRU(10)
+
+				l, err := Open(path, snapshotPath, source, WithRewriteSize(1))
+				require.NoError(t, err)
+				defer l.Close()
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				go l.Run(ctx)
+
+				require.NoError(t, put(l, source, "foo", "bar", 0))
+				require.Eventually(t, func() bool {
+					records, _, e := snapshot.Read(snapshotPath)
+					return e == nil && len(records) == 1 && l.Size() == 0
+				}, 3*time.Second, 10*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.operate(t, t.TempDir())
+		})
+	}
+}
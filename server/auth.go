@@ -0,0 +1,61 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/fadyat/speedy/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	AuthorizationHeader = "authorization"
+	BearerPrefix        = "Bearer "
+)
+
+var (
+	MissingAdminTokenMsg = "admin token is required"
+	InvalidAdminTokenMsg = "invalid admin token"
+	AdminDisabledMsg     = "admin calls are disabled on the node"
+
+	// adminMethods are the calls, which can harm the whole node, so they
+	// are available only with the admin token.
+	adminMethods = map[string]struct{}{
+		api.CacheService_Flush_FullMethodName: {},
+	}
+)
+
+// AdminInterceptor rejects calls of the admin methods without the valid
+// admin token, if the token is empty, admin methods are disabled at all.
+func AdminInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if _, ok := adminMethods[info.FullMethod]; !ok {
+			return handler(ctx, req)
+		}
+
+		if token == "" {
+			return nil, status.Error(codes.PermissionDenied, AdminDisabledMsg)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get(AuthorizationHeader)
+		if len(values) == 0 || !strings.HasPrefix(values[0], BearerPrefix) {
+			return nil, status.Error(codes.Unauthenticated, MissingAdminTokenMsg)
+		}
+
+		given := strings.TrimPrefix(values[0], BearerPrefix)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return nil, status.Error(codes.PermissionDenied, InvalidAdminTokenMsg)
+		}
+
+		return handler(ctx, req)
+	}
+}
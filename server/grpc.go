@@ -2,17 +2,33 @@ package server
 
 import (
 	"context"
+	"errors"
 	"github.com/fadyat/speedy/api"
 	"github.com/fadyat/speedy/eviction"
 	"github.com/fadyat/speedy/node"
+	"github.com/fadyat/speedy/oplog"
 	"github.com/fadyat/speedy/pkg"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultScanLimit = 100
+	maxScanLimit     = 1000
 )
 
 var (
 	KeyNotFoundMsg = "key not found"
+	NegativeTTLMsg = "ttl must not be negative"
+	ConflictMsg    = "version conflict"
+	SlowWatcherMsg = "watcher is too slow, subscribe again"
+	NotDurableMsg  = "write is applied, but not logged"
 )
 
 type CacheServer struct {
@@ -20,25 +36,317 @@ type CacheServer struct {
 
 	configPath string
 	cache      eviction.Algorithm
+	watches    *WatchRegistry
+	oplog      *oplog.Log
+	stats      stats
+
+	// keys are locked by writes until their events are published.
+	keys keyLocks
+}
+
+type Option func(*CacheServer)
+
+// WithOpLog sets the log, where all writes are appended, writes aren't
+// logged by default.
+func WithOpLog(l *oplog.Log) Option {
+	return func(s *CacheServer) {
+		s.oplog = l
+	}
+}
+
+// write calls fn, which changes the cache, and logs the returned
+// operations, if the log is enabled.
+func (s *CacheServer) write(fn func() []oplog.Op) error {
+	if s.oplog == nil {
+		fn()
+		return nil
+	}
+
+	if err := s.oplog.Write(fn); err != nil {
+		zap.L().Error("failed to log the operation", zap.Error(err))
+		return status.Error(codes.Internal, NotDurableMsg)
+	}
+
+	return nil
+}
+
+// WithWatchRegistry sets the registry, which is shared with the eviction
+// layer, to publish keys removed by the cache itself, by default only
+// changes made through the server are published.
+func WithWatchRegistry(r *WatchRegistry) Option {
+	return func(s *CacheServer) {
+		s.watches = r
+	}
 }
 
 func (s *CacheServer) Get(_ context.Context, req *api.GetRequest) (*api.GetResponse, error) {
-	if val, ok := s.cache.Get(req.Key); ok {
-		return &api.GetResponse{Value: val}, nil
+	val, version, ok := s.cache.Get(req.Key)
+	s.stats.get(ok)
+	if ok {
+		return &api.GetResponse{Value: val, Version: version}, nil
 	}
 
 	return nil, status.Error(codes.NotFound, KeyNotFoundMsg)
 }
 
 func (s *CacheServer) Put(_ context.Context, req *api.PutRequest) (*emptypb.Empty, error) {
-	s.cache.Put(req.Key, req.Value)
+	ttl := req.GetTtl().AsDuration()
+	if ttl < 0 {
+		return nil, status.Error(codes.InvalidArgument, NegativeTTLMsg)
+	}
+
+	unlock := s.keys.lock(req.Key)
+	defer unlock()
+
+	var version uint64
+	if err := s.write(func() []oplog.Op {
+		version = s.cache.Put(req.Key, req.Value, ttl)
+		return []oplog.Op{oplog.Put(req.Key, req.Value, version, ttl)}
+	}); err != nil {
+		return nil, err
+	}
+
+	s.stats.put()
+	s.watches.publishPut(req.Key, req.Value, version)
+	return &emptypb.Empty{}, nil
+}
+
+func (s *CacheServer) CompareAndSwap(
+	_ context.Context, req *api.CompareAndSwapRequest,
+) (*api.CompareAndSwapResponse, error) {
+	ttl := req.GetTtl().AsDuration()
+	if ttl < 0 {
+		return nil, status.Error(codes.InvalidArgument, NegativeTTLMsg)
+	}
+
+	unlock := s.keys.lock(req.Key)
+	defer unlock()
+
+	var (
+		version uint64
+		ok      bool
+	)
+
+	if err := s.write(func() []oplog.Op {
+		version, ok = s.cache.CompareAndSwap(req.Key, req.ExpectedVersion, req.Value, ttl)
+		if !ok {
+			return nil
+		}
+
+		return []oplog.Op{oplog.Put(req.Key, req.Value, version, ttl)}
+	}); err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, status.Error(codes.Aborted, ConflictMsg)
+	}
+
+	s.stats.put()
+	s.watches.publishPut(req.Key, req.Value, version)
+	return &api.CompareAndSwapResponse{Version: version}, nil
+}
+
+func (s *CacheServer) Incr(_ context.Context, req *api.IncrRequest) (*api.IncrResponse, error) {
+	ttl := req.GetTtl().AsDuration()
+	if ttl < 0 {
+		return nil, status.Error(codes.InvalidArgument, NegativeTTLMsg)
+	}
+
+	unlock := s.keys.lock(req.Key)
+	defer unlock()
+
+	var (
+		val     int64
+		version uint64
+		err     error
+	)
+
+	if e := s.write(func() []oplog.Op {
+		val, version, err = s.cache.Incr(req.Key, req.Delta, ttl)
+		if err != nil {
+			return nil
+		}
+
+		return []oplog.Op{oplog.Incr(s.cache, req.Key)}
+	}); e != nil {
+		return nil, e
+	}
+
+	switch {
+	case errors.Is(err, eviction.ErrNotInteger):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, eviction.ErrOverflow):
+		return nil, status.Error(codes.OutOfRange, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.stats.put()
+	s.watches.publishPut(req.Key, []byte(strconv.FormatInt(val, 10)), version)
+	return &api.IncrResponse{Value: val}, nil
+}
+
+func (s *CacheServer) MGet(_ context.Context, req *api.MGetRequest) (*api.MGetResponse, error) {
+	var values = make(map[string][]byte, len(req.Keys))
+	for _, key := range req.Keys {
+		val, _, ok := s.cache.Get(key)
+		s.stats.get(ok)
+		if ok {
+			values[key] = val
+		}
+	}
+
+	return &api.MGetResponse{Values: values}, nil
+}
+
+func (s *CacheServer) MPut(_ context.Context, req *api.MPutRequest) (*emptypb.Empty, error) {
+	for _, item := range req.Items {
+		if item.GetTtl().AsDuration() < 0 {
+			return nil, status.Error(codes.InvalidArgument, NegativeTTLMsg)
+		}
+	}
+
+	var keys = make([]string, 0, len(req.Items))
+	for _, item := range req.Items {
+		keys = append(keys, item.Key)
+	}
+
+	unlock := s.keys.lock(keys...)
+	defer unlock()
+
+	var versions = make([]uint64, len(req.Items))
+	if err := s.write(func() []oplog.Op {
+		ops := make([]oplog.Op, 0, len(req.Items))
+		for i, item := range req.Items {
+			ttl := item.GetTtl().AsDuration()
+			versions[i] = s.cache.Put(item.Key, item.Value, ttl)
+			ops = append(ops, oplog.Put(item.Key, item.Value, versions[i], ttl))
+		}
+
+		return ops
+	}); err != nil {
+		return nil, err
+	}
+
+	for i, item := range req.Items {
+		s.stats.put()
+		s.watches.publishPut(item.Key, item.Value, versions[i])
+	}
+
 	return &emptypb.Empty{}, nil
 }
 
+func (s *CacheServer) Delete(_ context.Context, req *api.DeleteRequest) (*emptypb.Empty, error) {
+	unlock := s.keys.lock(req.Key)
+	defer unlock()
+
+	var deleted bool
+	if err := s.write(func() []oplog.Op {
+		if deleted = s.cache.Delete(req.Key); !deleted {
+			return nil
+		}
+
+		return []oplog.Op{oplog.Delete(req.Key, s.cache.Version())}
+	}); err != nil {
+		return nil, err
+	}
+
+	if deleted {
+		s.watches.publishDelete(req.Key)
+		return &emptypb.Empty{}, nil
+	}
+
+	return nil, status.Error(codes.NotFound, KeyNotFoundMsg)
+}
+
 func (s *CacheServer) Len(_ context.Context, _ *emptypb.Empty) (*api.LengthResponse, error) {
 	return &api.LengthResponse{Length: s.cache.Len()}, nil
 }
 
+func (s *CacheServer) Stats(_ context.Context, _ *emptypb.Empty) (*api.StatsResponse, error) {
+	cacheStats := s.cache.Stats()
+	return &api.StatsResponse{
+		Gets:        s.stats.gets.Load(),
+		Hits:        s.stats.hits.Load(),
+		Misses:      s.stats.misses.Load(),
+		Puts:        s.stats.puts.Load(),
+		Evictions:   cacheStats.Evictions,
+		Expirations: cacheStats.Expirations,
+		Items:       cacheStats.Items,
+		Bytes:       cacheStats.Bytes,
+		Capacity:    cacheStats.Capacity,
+		Uptime:      durationpb.New(time.Since(s.stats.startedAt)),
+		Memory:      cacheStats.Memory,
+		MaxBytes:    cacheStats.MaxBytes,
+	}, nil
+}
+
+func (s *CacheServer) Flush(_ context.Context, req *api.FlushRequest) (*api.FlushResponse, error) {
+	var removed int
+	if err := s.write(func() []oplog.Op {
+		if removed = s.cache.Flush(req.Prefix); removed == 0 {
+			return nil
+		}
+
+		return []oplog.Op{oplog.Flush(req.Prefix, s.cache.Version())}
+	}); err != nil {
+		return nil, err
+	}
+
+	return &api.FlushResponse{Removed: uint64(removed)}, nil
+}
+
+func (s *CacheServer) Scan(_ context.Context, req *api.ScanRequest) (*api.ScanResponse, error) {
+	var limit = int(req.Limit)
+	switch {
+	case limit == 0:
+		limit = defaultScanLimit
+	case limit > maxScanLimit:
+		limit = maxScanLimit
+	}
+
+	entries, next := s.cache.Scan(req.Prefix, req.Cursor, limit)
+
+	var items = make([]*api.KeyValue, 0, len(entries))
+	for _, e := range entries {
+		item := &api.KeyValue{Key: e.Key}
+		if req.WithValues {
+			item.Value = e.Value
+		}
+
+		items = append(items, item)
+	}
+
+	return &api.ScanResponse{Items: items, NextCursor: next}, nil
+}
+
+func (s *CacheServer) Watch(req *api.WatchRequest, stream api.CacheService_WatchServer) error {
+	sub := s.watches.subscribe(req.Key, req.Prefix)
+	defer s.watches.unsubscribe(sub)
+
+	// headers are sent right after the subscription, so the client knows,
+	// that it won't miss the following changes.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-sub.events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, SlowWatcherMsg)
+			}
+
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (s *CacheServer) GetClusterConfig(_ context.Context, _ *emptypb.Empty) (*api.ClusterConfig, error) {
 	locallyStored, err := getLocallyStoredClusterConfig(s.configPath)
 	if err != nil {
@@ -63,9 +371,39 @@ func getLocallyStoredClusterConfig(path string) ([]*api.Node, error) {
 func NewCacheServer(
 	configPath string,
 	algo eviction.Algorithm,
+	opts ...Option,
 ) *CacheServer {
-	return &CacheServer{
+	s := &CacheServer{
 		configPath: configPath,
 		cache:      algo,
+		stats:      stats{startedAt: time.Now()},
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if s.watches == nil {
+		s.watches = NewWatchRegistry()
 	}
+
+	s.watches.setGuard(s.publishRemoval)
+	return s
+}
+
+// publishRemoval publishes the key, removed by the cache, under the lock of
+// the key, so it's ordered with the writes of the key, which publish their
+// events under the same lock.
+//
+// the removal is dropped, when the key is stored again, the event of the
+// newer write is already published, so watchers never see the stale one.
+func (s *CacheServer) publishRemoval(event *api.WatchEvent) {
+	unlock := s.keys.lock(event.Key)
+	defer unlock()
+
+	if _, ok := s.cache.Peek(event.Key); ok {
+		return
+	}
+
+	s.watches.publish(event)
 }
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"slices"
+	"sync"
+)
+
+// keyStripes is the number of locks, shared by all keys.
+const keyStripes = 256
+
+// keyLocks orders the writes of the same key together with their watch
+// events, the cache is unlocked before the event is published, so events
+// of the concurrent writes could reach watchers in the other order.
+//
+// keys are spread between the stripes, so writes of different keys rarely
+// wait for each other.
+type keyLocks struct {
+	stripes [keyStripes]sync.Mutex
+}
+
+// stripe returns the stripe of the key, using the inlined fnv-1a hash.
+func stripe(key string) int {
+	var h uint32 = 2166136261
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return int(h % keyStripes)
+}
+
+// lock locks the stripes of the keys in the ascending order, so batches
+// never deadlock, and returns the func, which unlocks them.
+func (l *keyLocks) lock(keys ...string) func() {
+	var stripes = make([]int, 0, len(keys))
+	for _, key := range keys {
+		stripes = append(stripes, stripe(key))
+	}
+
+	slices.Sort(stripes)
+	stripes = slices.Compact(stripes)
+
+	for _, i := range stripes {
+		l.stripes[i].Lock()
+	}
+
+	return func() {
+		for _, i := range stripes {
+			l.stripes[i].Unlock()
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/fadyat/speedy/eviction"
+	"go.uber.org/zap"
+)
+
+// RemovalLogger returns the eviction.Listener, which logs every removed
+// key and replaced value at the debug level.
+func RemovalLogger(lg *zap.Logger) eviction.Listener {
+	return func(key string, val []byte, reason eviction.Reason) {
+		lg.Debug("cache entry removed",
+			zap.String("key", key),
+			zap.Int("size", len(val)),
+			zap.Stringer("reason", reason),
+		)
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// stats are request level counters of the CacheServer, the state of the
+// cache itself is tracked by the eviction layer.
+type stats struct {
+	startedAt time.Time
+	gets      atomic.Uint64
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	puts      atomic.Uint64
+}
+
+func (s *stats) get(hit bool) {
+	s.gets.Add(1)
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
+
+func (s *stats) put() {
+	s.puts.Add(1)
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"github.com/fadyat/speedy/api"
+	"github.com/fadyat/speedy/eviction"
+	"strings"
+	"sync"
+)
+
+const (
+
+	// watchBufferSize is the number of events, which can be queued for
+	// a single watcher, before it's considered as a slow one.
+	watchBufferSize = 64
+)
+
+type subscription struct {
+	key    string
+	prefix bool
+	events chan *api.WatchEvent
+}
+
+func (s *subscription) matches(key string) bool {
+	if s.prefix {
+		return strings.HasPrefix(key, s.key)
+	}
+
+	return s.key == key
+}
+
+// WatchRegistry keeps subscriptions of the Watch calls and fans out
+// the key changes to them.
+//
+// changes, made through the CacheServer, are published by the server itself,
+// keys removed by the cache (evicted or expired) are published by the
+// eviction layer, via OnRemove listener.
+//
+// removals happen inside the writes of other keys, so they are queued and
+// published by a single goroutine, through the guard, which orders them
+// with the writes of the removed keys, see CacheServer.publishRemoval.
+type WatchRegistry struct {
+	mx   sync.RWMutex
+	subs map[*subscription]struct{}
+
+	removalsMx sync.Mutex
+	removals   []*api.WatchEvent
+	draining   bool
+	guard      func(event *api.WatchEvent)
+}
+
+func NewWatchRegistry() *WatchRegistry {
+	return &WatchRegistry{
+		subs: make(map[*subscription]struct{}),
+	}
+}
+
+func (r *WatchRegistry) subscribe(key string, prefix bool) *subscription {
+	sub := &subscription{
+		key:    key,
+		prefix: prefix,
+		events: make(chan *api.WatchEvent, watchBufferSize),
+	}
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	r.subs[sub] = struct{}{}
+	return sub
+}
+
+func (r *WatchRegistry) unsubscribe(sub *subscription) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if _, ok := r.subs[sub]; ok {
+		delete(r.subs, sub)
+		close(sub.events)
+	}
+}
+
+// publish never blocks, watchers, which don't keep up with the events,
+// are unsubscribed, that closes their channels.
+func (r *WatchRegistry) publish(event *api.WatchEvent) {
+	var slow []*subscription
+
+	r.mx.RLock()
+	for sub := range r.subs {
+		if !sub.matches(event.Key) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	r.mx.RUnlock()
+
+	for _, sub := range slow {
+		r.unsubscribe(sub)
+	}
+}
+
+// OnRemove is the eviction.Listener, which publishes keys removed by the
+// cache itself and flushed keys, deletes and puts are published by the
+// handlers.
+func (r *WatchRegistry) OnRemove(key string, _ []byte, reason eviction.Reason) {
+	var eventType api.WatchEvent_Type
+	switch reason {
+	case eviction.ReasonCapacity:
+		eventType = api.WatchEvent_EVICT
+	case eviction.ReasonExpired:
+		eventType = api.WatchEvent_EXPIRE
+	case eviction.ReasonFlushed:
+		eventType = api.WatchEvent_DELETE
+	default:
+		return
+	}
+
+	r.removalsMx.Lock()
+	defer r.removalsMx.Unlock()
+
+	r.removals = append(r.removals, &api.WatchEvent{Type: eventType, Key: key})
+	if !r.draining {
+		r.draining = true
+		go r.drain()
+	}
+}
+
+// setGuard sets the func, which publishes the queued removals, by default
+// they are published as is.
+func (r *WatchRegistry) setGuard(guard func(event *api.WatchEvent)) {
+	r.removalsMx.Lock()
+	defer r.removalsMx.Unlock()
+
+	r.guard = guard
+}
+
+// drain publishes the queued removals in their order, until the queue
+// is empty.
+func (r *WatchRegistry) drain() {
+	for {
+		r.removalsMx.Lock()
+		if len(r.removals) == 0 {
+			r.removals, r.draining = nil, false
+			r.removalsMx.Unlock()
+			return
+		}
+
+		event, guard := r.removals[0], r.guard
+		r.removals = r.removals[1:]
+		r.removalsMx.Unlock()
+
+		if guard == nil {
+			r.publish(event)
+			continue
+		}
+
+		guard(event)
+	}
+}
+
+func (r *WatchRegistry) publishPut(key string, val []byte, version uint64) {
+	r.publish(&api.WatchEvent{Type: api.WatchEvent_PUT, Key: key, Value: val, Version: version})
+}
+
+func (r *WatchRegistry) publishDelete(key string) {
+	r.publish(&api.WatchEvent{Type: api.WatchEvent_DELETE, Key: key})
+}
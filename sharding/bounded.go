@@ -0,0 +1,153 @@
+package sharding
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultEpsilon allows shards to take 25% more load than the average.
+const defaultEpsilon = 0.25
+
+// LoadTracker is implemented by algorithms, which take the load of shards
+// into account, the client reports the start and the end of every request
+// to the shard, returned by GetShard.
+type LoadTracker interface {
+	Acquire(shard *Shard)
+	Release(shard *Shard)
+}
+
+// PrimaryRouter is implemented by algorithms, which GetShard may return
+// another shard, than the owner of the key, GetPrimaryShard returns the
+// owner itself, so the key can be removed from it after the diverted write.
+type PrimaryRouter interface {
+	GetPrimaryShard(key string) *Shard
+}
+
+// boundedLoad is the consistent hashing with bounded loads, GetShard walks
+// the ring clockwise, past the shards, which load exceeds (1+ε) times the
+// average one, so hot keys are spread between the next shards.
+//
+// reads and writes of the key are expected to go to GetShard, so the next
+// shard is filled by the writes, while the owner is overloaded, the key is
+// removed from the owner after the diverted write, so the owner doesn't
+// return the old value later, see PrimaryRouter.
+//
+// capacity of the shard is ceil((1+ε) * (m+1) * w/W), where m is the number
+// of in-flight requests, w is the weight of the shard and W is the total
+// weight, so the key stays on its shard, until the shard is overloaded.
+type boundedLoad struct {
+	*consistent
+	epsilon float64
+
+	// loads are kept for deleted shards too, because their requests
+	// may be still in-flight, total includes them.
+	loadMx sync.RWMutex
+	loads  map[string]*atomic.Int64
+	total  atomic.Int64
+}
+
+type BoundedLoadOption func(*boundedLoad)
+
+// WithEpsilon sets the allowed excess of the average load, smaller epsilon
+// gives more even load, but moves more keys from their shards.
+func WithEpsilon(epsilon float64) BoundedLoadOption {
+	return func(b *boundedLoad) {
+		if epsilon >= 0 {
+			b.epsilon = epsilon
+		}
+	}
+}
+
+func NewBoundedLoad(
+	shards []*Shard,
+	hashFn func(key string) uint32,
+	opts ...BoundedLoadOption,
+) Algorithm {
+	b := &boundedLoad{
+		consistent: NewConsistent(shards, hashFn).(*consistent),
+		epsilon:    defaultEpsilon,
+		loads:      make(map[string]*atomic.Int64),
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	return b
+}
+
+func (b *boundedLoad) GetShard(key string) *Shard {
+	var hash = b.hash(key)
+
+	b.mx.RLock()
+	defer b.mx.RUnlock()
+
+	if len(b.orderedKeys) == 0 {
+		return nil
+	}
+
+	var (
+		start = sort.Search(len(b.orderedKeys), func(i int) bool {
+			return b.orderedKeys[i] >= hash
+		})
+		average = float64(b.total.Load()+1) / float64(b.weights) * (1 + b.epsilon)
+	)
+
+	// at least one shard isn't loaded more than the average, so the walk
+	// ends before the full circle.
+	for i := 0; i < len(b.orderedKeys); i++ {
+		shard := b.shards[b.orderedKeys[(start+i)%len(b.orderedKeys)]]
+		if float64(b.load(shard.ID)) < math.Ceil(average*float64(shard.weight())) {
+			return shard
+		}
+	}
+
+	return b.shards[b.orderedKeys[start%len(b.orderedKeys)]]
+}
+
+// GetPrimaryShard returns the owner of the key on the ring, regardless of
+// the load of shards.
+func (b *boundedLoad) GetPrimaryShard(key string) *Shard {
+	return b.consistent.GetShard(key)
+}
+
+func (b *boundedLoad) load(id string) int64 {
+	b.loadMx.RLock()
+	defer b.loadMx.RUnlock()
+
+	if l, ok := b.loads[id]; ok {
+		return l.Load()
+	}
+
+	return 0
+}
+
+func (b *boundedLoad) Acquire(shard *Shard) {
+	b.loadMx.RLock()
+	l, ok := b.loads[shard.ID]
+	b.loadMx.RUnlock()
+
+	if !ok {
+		b.loadMx.Lock()
+		if l, ok = b.loads[shard.ID]; !ok {
+			l = new(atomic.Int64)
+			b.loads[shard.ID] = l
+		}
+		b.loadMx.Unlock()
+	}
+
+	l.Add(1)
+	b.total.Add(1)
+}
+
+func (b *boundedLoad) Release(shard *Shard) {
+	b.loadMx.RLock()
+	defer b.loadMx.RUnlock()
+
+	if l, ok := b.loads[shard.ID]; ok {
+		l.Add(-1)
+		b.total.Add(-1)
+	}
+}
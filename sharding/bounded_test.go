@@ -0,0 +1,155 @@
+package sharding
+
+import (
+	"github.com/stretchr/testify/require"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// hotLoad routes requests of zipf distributed keys, which never end, and
+// returns the load of every shard.
+func hotLoad(algo Algorithm, requests int) map[string]int {
+	var (
+		zipf   = rand.NewZipf(rand.New(rand.NewSource(42)), 1.1, 1, 1000)
+		loads  = make(map[string]int)
+		lt, ok = algo.(LoadTracker)
+	)
+
+	for i := 0; i < requests; i++ {
+		shard := algo.GetShard("key" + strconv.FormatUint(zipf.Uint64(), 10))
+		if ok {
+			lt.Acquire(shard)
+		}
+
+		loads[shard.ID]++
+	}
+
+	return loads
+}
+
+func maxLoad(loads map[string]int) int {
+	var largest int
+	for _, l := range loads {
+		largest = max(largest, l)
+	}
+
+	return largest
+}
+
+func TestBoundedLoad_Flow(t *testing.T) {
+	testcases := []struct {
+		name   string
+		shards []*Shard
+		ops    func(s *boundedLoad)
+	}{
+		{
+			name: "get shard from empty ring",
+			ops: func(s *boundedLoad) {
+				require.Nil(t, s.GetShard("key"))
+			},
+		},
+		{
+			name:   "same as consistent without load",
+			shards: newShards(5),
+			ops: func(s *boundedLoad) {
+				ring := NewConsistent(newShards(5), crc32Hash)
+				for i := 0; i < 10_000; i++ {
+					key := "key" + strconv.Itoa(i)
+					require.Equal(t, ring.GetShard(key), s.GetShard(key))
+				}
+			},
+		},
+		{
+			name:   "stable while load is within bounds",
+			shards: newShards(5),
+			ops: func(s *boundedLoad) {
+				owner := s.GetShard("key")
+				for i := 0; i < 100; i++ {
+					shard := s.GetShard("key")
+					require.Equal(t, owner, shard)
+
+					// other shards are loaded, so the average is growing too.
+					s.Acquire(shard)
+					s.Release(shard)
+					for _, other := range s.GetShards() {
+						if other.ID != owner.ID {
+							s.Acquire(other)
+						}
+					}
+				}
+			},
+		},
+		{
+			name:   "overloaded shard is skipped",
+			shards: newShards(5),
+			ops: func(s *boundedLoad) {
+				owner := s.GetShard("key")
+				for i := 0; i < 10; i++ {
+					s.Acquire(owner)
+				}
+
+				next := s.GetShard("key")
+				require.NotEqual(t, owner, next)
+				require.Equal(t, owner, s.GetPrimaryShard("key"))
+
+				// the key returns to its shard, when the load is gone.
+				for i := 0; i < 10; i++ {
+					s.Release(owner)
+				}
+
+				require.Equal(t, owner, s.GetShard("key"))
+			},
+		},
+		{
+			name:   "requests to the deleted shard",
+			shards: newShards(2),
+			ops: func(s *boundedLoad) {
+				s.Acquire(&Shard{ID: "1"})
+				require.NoError(t, s.DeleteShard(&Shard{ID: "1"}))
+				s.Release(&Shard{ID: "1"})
+				s.Release(&Shard{ID: "unknown"})
+
+				require.Zero(t, s.total.Load())
+				require.Equal(t, &Shard{ID: "2"}, s.GetShard("key"))
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ops(NewBoundedLoad(tc.shards, crc32Hash).(*boundedLoad))
+		})
+	}
+}
+
+func TestBoundedLoad_Bounds(t *testing.T) {
+	const n, requests = 10, 10_000
+
+	unbounded := maxLoad(hotLoad(NewConsistent(newShards(n), crc32Hash), requests))
+
+	for _, epsilon := range []float64{0, 0.1, 0.25, 1} {
+		t.Run(strconv.FormatFloat(epsilon, 'f', -1, 64), func(t *testing.T) {
+			loads := hotLoad(NewBoundedLoad(newShards(n), crc32Hash, WithEpsilon(epsilon)), requests)
+			require.Len(t, loads, n)
+
+			bound := int(math.Ceil((1 + epsilon) * requests / n))
+			require.LessOrEqual(t, maxLoad(loads), bound)
+			require.Less(t, maxLoad(loads), unbounded)
+		})
+	}
+}
+
+func TestBoundedLoad_Weights(t *testing.T) {
+	const requests, epsilon = 10_000, 0.1
+
+	shards := newShards(3)
+	shards[0].Weight = 2
+
+	loads := hotLoad(NewBoundedLoad(shards, crc32Hash, WithEpsilon(epsilon)), requests)
+	for _, shard := range shards {
+		bound := math.Ceil((1 + epsilon) * requests * float64(shard.weight()) / 4)
+		require.LessOrEqual(t, float64(loads[shard.ID]), bound, shard.ID)
+	}
+}
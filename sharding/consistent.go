@@ -3,24 +3,57 @@ package sharding
 import (
 	"slices"
 	"sort"
+	"strings"
 	"sync"
 )
 
+// defaultVirtualNodes is the number of points of the shard with the weight 1
+// on the ring, it's the same as in ketama.
+const defaultVirtualNodes = 160
+
 type consistent struct {
-	mx          sync.RWMutex
+	mx sync.RWMutex
+
+	// shards are the points of the ring, every physical shard is placed
+	// on the ring multiple times, see virtualNodes.
 	shards      map[uint32]*Shard
 	orderedKeys []uint32
+	nodes       map[string]*Shard
 	hash        hashFn
+
+	// weights is the total weight of the physical shards.
+	weights int
+
+	virtualNodes int
+}
+
+type ConsistentOption func(*consistent)
+
+// WithVirtualNodes sets the number of points of the shard with the weight 1
+// on the ring, more points give more even distribution of keys, but slower
+// updates.
+func WithVirtualNodes(n int) ConsistentOption {
+	return func(c *consistent) {
+		if n > 0 {
+			c.virtualNodes = n
+		}
+	}
 }
 
 func NewConsistent(
 	shards []*Shard,
 	hashFn func(key string) uint32,
+	opts ...ConsistentOption,
 ) Algorithm {
 	c := &consistent{
-		hash:        hashFn,
-		shards:      make(map[uint32]*Shard),
-		orderedKeys: make([]uint32, 0, len(shards)),
+		hash:         hashFn,
+		shards:       make(map[uint32]*Shard),
+		nodes:        make(map[string]*Shard),
+		virtualNodes: defaultVirtualNodes,
+	}
+
+	for _, o := range opts {
+		o(c)
 	}
 
 	c.mx.Lock()
@@ -30,21 +63,24 @@ func NewConsistent(
 		logRegisterErr(c.registerShardUnsafe(shard))
 	}
 
-	slices.Sort(c.orderedKeys)
+	c.rebuild()
 	return c
 }
 
 func (c *consistent) GetShard(key string) *Shard {
-	var (
-		hash    = c.hash(key)
-		closest = sort.Search(len(c.orderedKeys), func(i int) bool {
-			return c.orderedKeys[i] >= hash
-		})
-	)
+	var hash = c.hash(key)
 
 	c.mx.RLock()
 	defer c.mx.RUnlock()
 
+	if len(c.orderedKeys) == 0 {
+		return nil
+	}
+
+	closest := sort.Search(len(c.orderedKeys), func(i int) bool {
+		return c.orderedKeys[i] >= hash
+	})
+
 	closest %= len(c.orderedKeys)
 	return c.shards[c.orderedKeys[closest]]
 }
@@ -57,51 +93,90 @@ func (c *consistent) RegisterShard(shard *Shard) error {
 		return err
 	}
 
-	slices.Sort(c.orderedKeys)
+	c.rebuild()
 	return nil
 }
 
 func (c *consistent) registerShardUnsafe(shard *Shard) error {
-	var hash = c.hash("node" + shard.ID)
-	if c.exists(hash) {
+	if err := shard.Validate(); err != nil {
+		return err
+	}
+
+	if _, ok := c.nodes[shard.ID]; ok {
 		return ErrShardAlreadyRegistered
 	}
 
-	c.shards[hash] = shard
-	c.orderedKeys = append(c.orderedKeys, hash)
+	c.nodes[shard.ID] = shard
 	return nil
 }
 
 func (c *consistent) DeleteShard(shard *Shard) error {
-	var hash = c.hash("node" + shard.ID)
-
 	c.mx.Lock()
 	defer c.mx.Unlock()
 
-	idx, found := slices.BinarySearch(c.orderedKeys, hash)
-	if !found {
+	if _, ok := c.nodes[shard.ID]; !ok {
 		return ErrShardNotFound
 	}
 
-	delete(c.shards, hash)
-	c.orderedKeys = slices.Delete(c.orderedKeys, idx, idx+1)
+	delete(c.nodes, shard.ID)
+	c.rebuild()
 	return nil
 }
 
-func (c *consistent) exists(hash uint32) bool {
-	_, ok := c.shards[hash]
-	return ok
+// point returns the hash of the i-th point of the shard, the first point
+// is the same as the ring without virtual nodes has.
+//
+// other points are derived from the first one with mix32, hashes of the
+// similar keys would form clusters on the ring.
+func (c *consistent) point(id string, i int) uint32 {
+	h := c.hash("node" + id)
+	if i == 0 {
+		return h
+	}
+
+	return mix32(h + uint32(i)*0x9e3779b9)
 }
 
-func (c *consistent) GetShards() []*Shard {
-	var shards = make([]*Shard, 0, len(c.shards))
+// rebuild places all shards on the ring from scratch, the number of
+// points of the shard is proportional to its weight.
+//
+// points with the same hash are owned by the shard with the smallest ID,
+// so the ring doesn't depend on the order of registrations, and the point
+// is passed to the next shard, when the owner is deleted.
+func (c *consistent) rebuild() {
+	clear(c.shards)
+	c.weights = 0
+	for _, shard := range c.nodes {
+		c.weights += shard.weight()
+		for i := 0; i < c.virtualNodes*shard.weight(); i++ {
+			hash := c.point(shard.ID, i)
+			if owner, ok := c.shards[hash]; !ok || shard.ID < owner.ID {
+				c.shards[hash] = shard
+			}
+		}
+	}
 
+	c.orderedKeys = c.orderedKeys[:0]
+	for hash := range c.shards {
+		c.orderedKeys = append(c.orderedKeys, hash)
+	}
+
+	slices.Sort(c.orderedKeys)
+}
+
+// GetShards returns the registered physical shards, ordered by their IDs.
+func (c *consistent) GetShards() []*Shard {
 	c.mx.RLock()
 	defer c.mx.RUnlock()
 
-	for _, hash := range c.orderedKeys {
-		shards = append(shards, c.shards[hash])
+	var shards = make([]*Shard, 0, len(c.nodes))
+	for _, shard := range c.nodes {
+		shards = append(shards, shard)
 	}
 
+	slices.SortFunc(shards, func(a, b *Shard) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
 	return shards
 }
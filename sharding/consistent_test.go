@@ -1,8 +1,13 @@
 package sharding
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"hash/fnv"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -110,11 +115,32 @@ func TestConsistent_Flow(t *testing.T) {
 				{ID: "200"},
 			},
 			ops: func(s *consistent, _ hashFn) {
-				shards := s.GetShards()
-				for i := range s.orderedKeys {
-					storedShard := s.shards[s.orderedKeys[i]]
-					require.Equal(t, storedShard, shards[i])
-				}
+				require.Equal(t, []*Shard{{ID: "1000000000"}, {ID: "200"}}, s.GetShards())
+			},
+		},
+		{
+			name: "get shard from empty ring",
+			ops: func(s *consistent, _ hashFn) {
+				require.Nil(t, s.GetShard("key"))
+			},
+		},
+		{
+			name: "hash collision",
+			shards: []*Shard{
+				{ID: "2"},
+				{ID: "1"},
+			},
+			ops: func(s *consistent, _ hashFn) {
+				// both shards are placed at 1, the smallest ID owns the point.
+				require.Equal(t, []*Shard{{ID: "1"}, {ID: "2"}}, s.GetShards())
+				require.Equal(t, []uint32{1}, s.orderedKeys)
+				require.Equal(t, &Shard{ID: "1"}, s.GetShard("k"))
+
+				require.NoError(t, s.DeleteShard(&Shard{ID: "1"}))
+				require.Equal(t, &Shard{ID: "2"}, s.GetShard("k"))
+
+				require.NoError(t, s.RegisterShard(&Shard{ID: "1"}))
+				require.Equal(t, &Shard{ID: "1"}, s.GetShard("k"))
 			},
 		},
 		{
@@ -146,7 +172,7 @@ func TestConsistent_Flow(t *testing.T) {
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			s := NewConsistent(tc.shards, ln).(*consistent)
+			s := NewConsistent(tc.shards, ln, WithVirtualNodes(1)).(*consistent)
 			tc.ops(s, ln)
 
 			require.True(t, sort.SliceIsSorted(s.orderedKeys, func(i, j int) bool {
@@ -155,3 +181,96 @@ func TestConsistent_Flow(t *testing.T) {
 		})
 	}
 }
+
+func crc32Hash(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+func fnvHash(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+func newShards(n int) []*Shard {
+	shards := make([]*Shard, 0, n)
+	for i := 1; i <= n; i++ {
+		shards = append(shards, &Shard{ID: strconv.Itoa(i)})
+	}
+
+	return shards
+}
+
+// distribution returns the number of keys owned by every shard.
+func distribution(algo Algorithm, keys int) map[string]int {
+	owned := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		owned[algo.GetShard("key"+strconv.Itoa(i)).ID]++
+	}
+
+	return owned
+}
+
+func TestConsistent_VirtualNodes(t *testing.T) {
+	s := NewConsistent(newShards(5), crc32Hash).(*consistent)
+	require.Len(t, s.GetShards(), 5)
+	require.Len(t, s.orderedKeys, 5*defaultVirtualNodes)
+
+	before := make(map[string]string)
+	for i := 0; i < 10000; i++ {
+		key := "key" + strconv.Itoa(i)
+		before[key] = s.GetShard(key).ID
+	}
+
+	require.NoError(t, s.DeleteShard(&Shard{ID: "3"}))
+	require.Len(t, s.GetShards(), 4)
+	require.Len(t, s.orderedKeys, 4*defaultVirtualNodes)
+
+	// only keys of the deleted shard are moved.
+	for key, id := range before {
+		if id != "3" {
+			require.Equal(t, id, s.GetShard(key).ID, key)
+		}
+	}
+}
+
+func TestConsistent_Balance(t *testing.T) {
+	const keys = 100_000
+
+	// the share of the shard is the sum of its arcs, with v points its
+	// relative deviation is about 1/sqrt(v), 4 deviations are allowed.
+	maxDeviation := 4 / math.Sqrt(defaultVirtualNodes)
+
+	for name, hash := range map[string]hashFn{"crc32": crc32Hash, "fnv": fnvHash} {
+		for _, n := range []int{3, 5, 10} {
+			t.Run(fmt.Sprintf("%s/%d shards", name, n), func(t *testing.T) {
+				owned := distribution(NewConsistent(newShards(n), hash), keys)
+				require.Len(t, owned, n)
+
+				mean := float64(keys) / float64(n)
+				for id, count := range owned {
+					require.InDelta(t, 1, float64(count)/mean, maxDeviation, "shard %s", id)
+				}
+
+				// single point per shard is much worse.
+				single := distribution(NewConsistent(newShards(n), hash, WithVirtualNodes(1)), keys)
+				require.Greater(t, spread(single, n, keys), spread(owned, n, keys))
+			})
+		}
+	}
+}
+
+// spread returns the largest relative deviation of the shard share from
+// the mean.
+func spread(owned map[string]int, shards, keys int) float64 {
+	var (
+		mean    = float64(keys) / float64(shards)
+		largest float64
+	)
+
+	for i := 1; i <= shards; i++ {
+		largest = max(largest, math.Abs(float64(owned[strconv.Itoa(i)])/mean-1))
+	}
+
+	return largest
+}
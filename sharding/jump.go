@@ -0,0 +1,160 @@
+package sharding
+
+import (
+	"cmp"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// jump is the Lamping–Veach jump consistent hash, it maps the key to one of
+// the buckets without any state, except the ordered list of buckets.
+//
+// every shard has as many buckets, as its weight is, buckets are built from
+// the shards, ordered by their IDs, so all clients with the same shards have
+// the same buckets, regardless of the order of registrations and deletions.
+//
+// IDs are ordered by their length first, so numeric IDs are in the numeric
+// order, and the shard with the next ID is appended, only the keys, which
+// are moved to its buckets, change their shard.
+//
+// jump hash supports removing only the last bucket, so when the middle shard
+// is deleted, keys of all shards after it are moved, shards are expected to
+// be added and deleted from the end.
+type jump struct {
+	mx      sync.RWMutex
+	shards  map[string]*Shard
+	keys    []string
+	buckets []string
+	hash    func(key string) uint64
+}
+
+// fnv64a is the default 64-bit key hash of jump, 32-bit hashes leave
+// half of the jump state empty.
+func fnv64a(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func NewJump(
+	shards []*Shard,
+	hashFn func(key string) uint64,
+) Algorithm {
+	j := &jump{
+		hash:   hashFn,
+		shards: make(map[string]*Shard),
+		keys:   make([]string, 0, len(shards)),
+	}
+
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	for _, shard := range shards {
+		logRegisterErr(j.registerShardUnsafe(shard))
+	}
+
+	return j
+}
+
+// jumpHash returns the bucket of the key in [0, buckets).
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+
+	return int(b)
+}
+
+func (j *jump) GetShard(key string) *Shard {
+	var hash = j.hash(key)
+
+	j.mx.RLock()
+	defer j.mx.RUnlock()
+
+	if len(j.buckets) == 0 {
+		return nil
+	}
+
+	return j.shards[j.buckets[jumpHash(hash, len(j.buckets))]]
+}
+
+func (j *jump) RegisterShard(shard *Shard) error {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	return j.registerShardUnsafe(shard)
+}
+
+func (j *jump) registerShardUnsafe(shard *Shard) error {
+	if err := shard.Validate(); err != nil {
+		return err
+	}
+
+	if j.exists(shard) {
+		return ErrShardAlreadyRegistered
+	}
+
+	j.shards[shard.ID] = shard
+	j.keys = append(j.keys, shard.ID)
+	j.rebuild()
+	return nil
+}
+
+// compareIDs orders IDs by their length first, then lexicographically.
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		return cmp.Compare(len(a), len(b))
+	}
+
+	return strings.Compare(a, b)
+}
+
+// rebuild fills the buckets from scratch in the order of shard IDs.
+func (j *jump) rebuild() {
+	ids := slices.Clone(j.keys)
+	slices.SortFunc(ids, compareIDs)
+
+	j.buckets = j.buckets[:0]
+	for _, id := range ids {
+		for i := 0; i < j.shards[id].weight(); i++ {
+			j.buckets = append(j.buckets, id)
+		}
+	}
+}
+
+func (j *jump) DeleteShard(shard *Shard) error {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	if !j.exists(shard) {
+		return ErrShardNotFound
+	}
+
+	delete(j.shards, shard.ID)
+	idx := slices.Index(j.keys, shard.ID)
+	j.keys = slices.Delete(j.keys, idx, idx+1)
+	j.rebuild()
+	return nil
+}
+
+func (j *jump) exists(shard *Shard) bool {
+	_, ok := j.shards[shard.ID]
+	return ok
+}
+
+func (j *jump) GetShards() []*Shard {
+	j.mx.RLock()
+	defer j.mx.RUnlock()
+
+	shards := make([]*Shard, 0, len(j.keys))
+	for _, key := range j.keys {
+		shards = append(shards, j.shards[key])
+	}
+
+	return shards
+}
@@ -0,0 +1,188 @@
+package sharding
+
+import (
+	"github.com/stretchr/testify/require"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestJump_Flow(t *testing.T) {
+	testcases := []struct {
+		name   string
+		shards []*Shard
+		ops    func(s *jump)
+	}{
+		{
+			name: "register shard",
+			ops: func(s *jump) {
+				require.NoError(t, s.RegisterShard(&Shard{ID: "1", Weight: 2}))
+				require.Equal(t, 1, len(s.shards))
+				require.Equal(t, []string{"1", "1"}, s.buckets)
+			},
+		},
+		{
+			name:   "register shard twice",
+			shards: []*Shard{{ID: "1"}},
+			ops: func(s *jump) {
+				err := s.RegisterShard(&Shard{ID: "1"})
+				require.Equal(t, ErrShardAlreadyRegistered, err)
+			},
+		},
+		{
+			name:   "delete last shard",
+			shards: []*Shard{{ID: "1"}, {ID: "2"}, {ID: "3"}},
+			ops: func(s *jump) {
+				require.NoError(t, s.DeleteShard(&Shard{ID: "3"}))
+				require.Equal(t, []string{"1", "2"}, s.buckets)
+			},
+		},
+		{
+			name:   "delete middle shard",
+			shards: []*Shard{{ID: "1"}, {ID: "2", Weight: 2}, {ID: "3"}, {ID: "4", Weight: 2}},
+			ops: func(s *jump) {
+				require.NoError(t, s.DeleteShard(&Shard{ID: "2"}))
+				require.Equal(t, []string{"1", "3", "4", "4"}, s.buckets)
+				require.Equal(t, []*Shard{{ID: "1"}, {ID: "3"}, {ID: "4", Weight: 2}}, s.GetShards())
+			},
+		},
+		{
+			name: "delete shard not found",
+			ops: func(s *jump) {
+				err := s.DeleteShard(&Shard{ID: "1"})
+				require.Equal(t, ErrShardNotFound, err)
+			},
+		},
+		{
+			name: "get shard from empty list",
+			ops: func(s *jump) {
+				require.Nil(t, s.GetShard("key"))
+			},
+		},
+		{
+			name:   "get shards",
+			shards: []*Shard{{ID: "2", Weight: 3}, {ID: "1"}},
+			ops: func(s *jump) {
+				require.Equal(t, []*Shard{{ID: "2", Weight: 3}, {ID: "1"}}, s.GetShards())
+			},
+		},
+		{
+			name:   "buckets are ordered by IDs",
+			shards: []*Shard{{ID: "10"}, {ID: "2", Weight: 2}, {ID: "1"}, {ID: "b"}, {ID: "a"}},
+			ops: func(s *jump) {
+				require.Equal(t, []string{"1", "2", "2", "a", "b", "10"}, s.buckets)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ops(NewJump(tc.shards, fnv64a).(*jump))
+		})
+	}
+}
+
+// owners returns the shard of every key.
+func owners(algo Algorithm, keys int) []string {
+	ids := make([]string, 0, keys)
+	for i := 0; i < keys; i++ {
+		ids = append(ids, algo.GetShard("key"+strconv.Itoa(i)).ID)
+	}
+
+	return ids
+}
+
+func TestJump_Movement(t *testing.T) {
+	const keys, n = 100_000, 10
+
+	testcases := []struct {
+		name    string
+		change  func(s Algorithm)
+		allowed func(before, after string) bool
+
+		// moved is the expected share of moved keys.
+		moved float64
+	}{
+		{
+			name: "append shard",
+			change: func(s Algorithm) {
+				require.NoError(t, s.RegisterShard(&Shard{ID: strconv.Itoa(n + 1)}))
+			},
+			allowed: func(_, after string) bool {
+				return after == strconv.Itoa(n+1)
+			},
+			moved: 1.0 / (n + 1),
+		},
+		{
+			name: "delete last shard",
+			change: func(s Algorithm) {
+				require.NoError(t, s.DeleteShard(&Shard{ID: strconv.Itoa(n)}))
+			},
+			allowed: func(before, _ string) bool {
+				return before == strconv.Itoa(n)
+			},
+			moved: 1.0 / n,
+		},
+		{
+			name: "delete middle shard",
+			change: func(s Algorithm) {
+				require.NoError(t, s.DeleteShard(&Shard{ID: "3"}))
+			},
+			allowed: func(before, _ string) bool {
+				// keys of the shards before the deleted one stay.
+				id, _ := strconv.Atoi(before)
+				return id >= 3
+			},
+			// keys of the last bucket, which jump to its new place, stay.
+			moved: float64(n-2)/n - 1.0/n/(n-1),
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewJump(newShards(n), fnv64a)
+			before := owners(s, keys)
+			tc.change(s)
+			after := owners(s, keys)
+
+			var moved int
+			for i := range before {
+				if before[i] != after[i] {
+					require.True(t, tc.allowed(before[i], after[i]), "%s -> %s", before[i], after[i])
+					moved++
+				}
+			}
+
+			require.InDelta(t, tc.moved, float64(moved)/keys, 0.01)
+		})
+	}
+}
+
+func TestJump_Balance(t *testing.T) {
+	const keys = 100_000
+
+	for _, n := range []int{3, 5, 10, 100} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			owned := distribution(NewJump(newShards(n), fnv64a), keys)
+			require.Len(t, owned, n)
+
+			// keys are distributed independently, so the relative deviation
+			// of the share is sqrt((n-1)/keys), 5 deviations are allowed.
+			require.Less(t, spread(owned, n, keys), 5*math.Sqrt(float64(n-1)/keys))
+		})
+	}
+}
+
+func TestJump_History(t *testing.T) {
+	const keys = 10_000
+
+	// the client, which saw the deletion, routes keys the same way, as the
+	// client, which was created after it.
+	s := NewJump(newShards(5), fnv64a)
+	require.NoError(t, s.DeleteShard(&Shard{ID: "2"}))
+	require.NoError(t, s.RegisterShard(&Shard{ID: "2"}))
+	require.NoError(t, s.DeleteShard(&Shard{ID: "4"}))
+
+	fresh := NewJump([]*Shard{{ID: "5"}, {ID: "3"}, {ID: "2"}, {ID: "1"}}, fnv64a)
+	require.Equal(t, owners(fresh, keys), owners(s, keys))
+}
@@ -0,0 +1,212 @@
+package sharding
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// defaultTableSize is the size of the lookup table, it must be prime and
+// much larger than the number of shards, 65537 is used by the paper.
+const defaultTableSize = 65537
+
+// maglev is the Maglev hashing, keys are mapped to the shards through the
+// precomputed lookup table, so GetShard is O(1) and doesn't depend on the
+// number of shards.
+//
+// every shard has its own permutation of the table slots, shards take turns
+// to fill their next preferred empty slot, until the table is full, shard
+// with the weight w takes w slots per turn. The table is rebuilt on every
+// change, small part of the slots is moved to other shards, in addition to
+// the slots of the changed shard.
+type maglev struct {
+	mx     sync.RWMutex
+	shards map[string]*Shard
+	keys   []string
+	table  []*Shard
+	size   uint64
+	hash   hashFn
+}
+
+type MaglevOption func(*maglev)
+
+// WithTableSize sets the size of the lookup table, it's rounded up to the
+// next prime, the table should be at least 100 times larger, than the
+// number of shards, for the even distribution.
+func WithTableSize(size uint64) MaglevOption {
+	return func(m *maglev) {
+		m.size = nextPrime(max(size, 2))
+	}
+}
+
+func nextPrime(n uint64) uint64 {
+	for ; ; n++ {
+		prime := true
+		for d := uint64(2); d*d <= n; d++ {
+			if n%d == 0 {
+				prime = false
+				break
+			}
+		}
+
+		if prime {
+			return n
+		}
+	}
+}
+
+func NewMaglev(
+	shards []*Shard,
+	hashFn func(key string) uint32,
+	opts ...MaglevOption,
+) Algorithm {
+	m := &maglev{
+		hash:   hashFn,
+		shards: make(map[string]*Shard),
+		keys:   make([]string, 0, len(shards)),
+		size:   defaultTableSize,
+	}
+
+	for _, o := range opts {
+		o(m)
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	for _, shard := range shards {
+		logRegisterErr(m.registerShardUnsafe(shard))
+	}
+
+	m.rebuild()
+	return m
+}
+
+func (m *maglev) GetShard(key string) *Shard {
+	var hash = uint64(m.hash(key))
+
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	if len(m.table) == 0 {
+		return nil
+	}
+
+	return m.table[hash%m.size]
+}
+
+func (m *maglev) RegisterShard(shard *Shard) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if err := m.registerShardUnsafe(shard); err != nil {
+		return err
+	}
+
+	m.rebuild()
+	return nil
+}
+
+func (m *maglev) registerShardUnsafe(shard *Shard) error {
+	if err := shard.Validate(); err != nil {
+		return err
+	}
+
+	if m.exists(shard) {
+		return ErrShardAlreadyRegistered
+	}
+
+	m.shards[shard.ID] = shard
+	m.keys = append(m.keys, shard.ID)
+	return nil
+}
+
+func (m *maglev) DeleteShard(shard *Shard) error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	if !m.exists(shard) {
+		return ErrShardNotFound
+	}
+
+	delete(m.shards, shard.ID)
+	idx := slices.Index(m.keys, shard.ID)
+	m.keys = slices.Delete(m.keys, idx, idx+1)
+	m.rebuild()
+	return nil
+}
+
+// permutation is the order, in which the shard prefers the table slots,
+// the j-th preferred slot is (offset + j*skip) mod size, size is prime,
+// so every slot is visited.
+type permutation struct {
+	shard        *Shard
+	offset, skip uint64
+	next         uint64
+}
+
+// rebuild fills the lookup table from scratch, shards take turns in the
+// order of their IDs, so the table doesn't depend on the order of
+// registrations.
+func (m *maglev) rebuild() {
+	if len(m.shards) == 0 {
+		m.table = nil
+		return
+	}
+
+	perms := make([]*permutation, 0, len(m.shards))
+	for _, shard := range m.shards {
+		h := m.hash("node" + shard.ID)
+		perms = append(perms, &permutation{
+			shard:  shard,
+			offset: uint64(mix32(h)) % m.size,
+			skip:   uint64(mix32(h^0x9e3779b9))%(m.size-1) + 1,
+		})
+	}
+
+	slices.SortFunc(perms, func(a, b *permutation) int {
+		return strings.Compare(a.shard.ID, b.shard.ID)
+	})
+
+	var (
+		table  = make([]*Shard, m.size)
+		filled uint64
+	)
+
+	for {
+		for _, p := range perms {
+			for w := 0; w < p.shard.weight(); w++ {
+				slot := (p.offset + p.next*p.skip) % m.size
+				for table[slot] != nil {
+					p.next++
+					slot = (p.offset + p.next*p.skip) % m.size
+				}
+
+				table[slot] = p.shard
+				p.next++
+				filled++
+				if filled == m.size {
+					m.table = table
+					return
+				}
+			}
+		}
+	}
+}
+
+func (m *maglev) exists(shard *Shard) bool {
+	_, ok := m.shards[shard.ID]
+	return ok
+}
+
+func (m *maglev) GetShards() []*Shard {
+	m.mx.RLock()
+	defer m.mx.RUnlock()
+
+	shards := make([]*Shard, 0, len(m.keys))
+	for _, key := range m.keys {
+		shards = append(shards, m.shards[key])
+	}
+
+	return shards
+}
@@ -0,0 +1,199 @@
+package sharding
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestMaglev_Flow(t *testing.T) {
+	testcases := []struct {
+		name   string
+		shards []*Shard
+		ops    func(s *maglev)
+	}{
+		{
+			name: "register shard",
+			ops: func(s *maglev) {
+				require.NoError(t, s.RegisterShard(&Shard{ID: "1"}))
+				require.Equal(t, 1, len(s.shards))
+				require.Len(t, s.table, defaultTableSize)
+				require.Equal(t, &Shard{ID: "1"}, s.GetShard("key"))
+			},
+		},
+		{
+			name:   "register shard twice",
+			shards: []*Shard{{ID: "1"}},
+			ops: func(s *maglev) {
+				err := s.RegisterShard(&Shard{ID: "1"})
+				require.Equal(t, ErrShardAlreadyRegistered, err)
+			},
+		},
+		{
+			name:   "delete shard",
+			shards: []*Shard{{ID: "1"}, {ID: "2"}},
+			ops: func(s *maglev) {
+				require.NoError(t, s.DeleteShard(&Shard{ID: "1"}))
+				require.Equal(t, 1, len(s.shards))
+				require.NotContains(t, s.table, &Shard{ID: "1"})
+			},
+		},
+		{
+			name:   "delete all shards",
+			shards: []*Shard{{ID: "1"}},
+			ops: func(s *maglev) {
+				require.NoError(t, s.DeleteShard(&Shard{ID: "1"}))
+				require.Nil(t, s.GetShard("key"))
+			},
+		},
+		{
+			name: "delete shard not found",
+			ops: func(s *maglev) {
+				err := s.DeleteShard(&Shard{ID: "1"})
+				require.Equal(t, ErrShardNotFound, err)
+			},
+		},
+		{
+			name: "get shard from empty table",
+			ops: func(s *maglev) {
+				require.Nil(t, s.GetShard("key"))
+			},
+		},
+		{
+			name:   "get shards",
+			shards: []*Shard{{ID: "2"}, {ID: "1"}},
+			ops: func(s *maglev) {
+				require.Equal(t, []*Shard{{ID: "2"}, {ID: "1"}}, s.GetShards())
+			},
+		},
+		{
+			name:   "table doesn't depend on the order of registrations",
+			shards: []*Shard{{ID: "2"}, {ID: "1"}, {ID: "3"}},
+			ops: func(s *maglev) {
+				other := NewMaglev([]*Shard{{ID: "3"}, {ID: "1"}, {ID: "2"}}, crc32Hash).(*maglev)
+				require.Equal(t, other.table, s.table)
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.ops(NewMaglev(tc.shards, crc32Hash).(*maglev))
+		})
+	}
+}
+
+func TestMaglev_TableSize(t *testing.T) {
+	for size, expected := range map[uint64]uint64{0: 2, 2: 2, 4: 5, 100: 101, 65537: 65537} {
+		s := NewMaglev(newShards(1), crc32Hash, WithTableSize(size)).(*maglev)
+		require.Equal(t, expected, s.size)
+		require.Len(t, s.table, int(expected))
+	}
+}
+
+func TestMaglev_Balance(t *testing.T) {
+	for _, n := range []int{3, 10, 30} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			shards := newShards(n)
+			shards[0].Weight = 3
+
+			s := NewMaglev(shards, crc32Hash).(*maglev)
+			slots := make(map[string]int)
+			for _, shard := range s.table {
+				slots[shard.ID]++
+			}
+
+			// shards take the same number of slots per turn, so they differ
+			// at most by the slots of the last turn.
+			perWeight := float64(defaultTableSize) / float64(n+2)
+			for _, shard := range shards {
+				require.InDelta(t, perWeight*float64(shard.weight()), slots[shard.ID], float64(shard.weight()))
+			}
+		})
+	}
+}
+
+// disruption returns the share of keys, which changed the shard after
+// the change, and the share of moved keys, which weren't owned by the
+// changed shard before or after.
+func disruption(algo Algorithm, change func(Algorithm), changed string) (float64, float64) {
+	const keys = 100_000
+
+	before := owners(algo, keys)
+	change(algo)
+	after := owners(algo, keys)
+
+	var moved, unrelated int
+	for i := range before {
+		if before[i] == after[i] {
+			continue
+		}
+
+		moved++
+		if before[i] != changed && after[i] != changed {
+			unrelated++
+		}
+	}
+
+	return float64(moved) / keys, float64(unrelated) / keys
+}
+
+func TestMaglev_Disruption(t *testing.T) {
+	for _, n := range []int{10, 30} {
+		changes := map[string]struct {
+			change  func(Algorithm)
+			changed string
+
+			// share is the share of keys owned by the changed shard.
+			share float64
+		}{
+			"delete": {
+				change:  func(s Algorithm) { require.NoError(t, s.DeleteShard(&Shard{ID: "2"})) },
+				changed: "2",
+				share:   1 / float64(n),
+			},
+			"register": {
+				change:  func(s Algorithm) { require.NoError(t, s.RegisterShard(&Shard{ID: "new"})) },
+				changed: "new",
+				share:   1 / float64(n+1),
+			},
+		}
+
+		for name, c := range changes {
+			t.Run(fmt.Sprintf("%s/%d", name, n), func(t *testing.T) {
+				rendezvousMoved, rendezvousUnrelated := disruption(NewRendezvous(newShards(n), crc32Hash), c.change, c.changed)
+				maglevMoved, maglevUnrelated := disruption(NewMaglev(newShards(n), crc32Hash), c.change, c.changed)
+
+				// rendezvous moves only keys of the changed shard.
+				require.Zero(t, rendezvousUnrelated)
+				require.InDelta(t, c.share, rendezvousMoved, 0.005)
+
+				// maglev moves some unrelated keys too, but not much more.
+				require.Less(t, maglevMoved, 2*rendezvousMoved)
+				require.Less(t, maglevUnrelated, c.share)
+			})
+		}
+	}
+}
+
+func BenchmarkGetShard(b *testing.B) {
+	for _, algo := range []AlgorithmType{RendezvousAlgorithm, ConsistentAlgorithm, JumpAlgorithm, MaglevAlgorithm} {
+		for _, n := range []int{3, 30, 100} {
+			b.Run(fmt.Sprintf("%s/%d", algo, n), func(b *testing.B) {
+				s, err := NewAlgo(algo, newShards(n), crc32Hash)
+				require.NoError(b, err)
+
+				keys := make([]string, 1024)
+				for i := range keys {
+					keys[i] = "key" + strconv.Itoa(i)
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					s.GetShard(keys[i%len(keys)])
+				}
+			})
+		}
+	}
+}
@@ -10,6 +10,10 @@ type naive struct {
 	shards map[string]*Shard
 	keys   []string
 	hash   hashFn
+
+	// buckets keep the shard ID as many times, as its weight is, keys
+	// are distributed between buckets.
+	buckets []string
 }
 
 func NewNaive(
@@ -36,8 +40,12 @@ func (n *naive) GetShard(key string) *Shard {
 	n.mx.RLock()
 	defer n.mx.RUnlock()
 
-	idx := n.hash(key) % uint32(len(n.keys))
-	return n.shards[n.keys[idx]]
+	if len(n.buckets) == 0 {
+		return nil
+	}
+
+	idx := n.hash(key) % uint32(len(n.buckets))
+	return n.shards[n.buckets[idx]]
 }
 
 func (n *naive) RegisterShard(shard *Shard) error {
@@ -48,12 +56,20 @@ func (n *naive) RegisterShard(shard *Shard) error {
 }
 
 func (n *naive) registerShardUnsafe(shard *Shard) error {
+	if err := shard.Validate(); err != nil {
+		return err
+	}
+
 	if n.exists(shard) {
 		return ErrShardAlreadyRegistered
 	}
 
 	n.shards[shard.ID] = shard
 	n.keys = append(n.keys, shard.ID)
+	for i := 0; i < shard.weight(); i++ {
+		n.buckets = append(n.buckets, shard.ID)
+	}
+
 	return nil
 }
 
@@ -68,6 +84,10 @@ func (n *naive) DeleteShard(shard *Shard) error {
 	delete(n.shards, shard.ID)
 	idx := slices.Index(n.keys, shard.ID)
 	n.keys = slices.Delete(n.keys, idx, idx+1)
+	n.buckets = slices.DeleteFunc(n.buckets, func(id string) bool {
+		return id == shard.ID
+	})
+
 	return nil
 }
 
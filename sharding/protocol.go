@@ -12,6 +12,13 @@ const (
 	NaiveAlgorithm      AlgorithmType = "naive"
 	RendezvousAlgorithm AlgorithmType = "rendezvous"
 	ConsistentAlgorithm AlgorithmType = "consistent"
+	JumpAlgorithm       AlgorithmType = "jump"
+	MaglevAlgorithm     AlgorithmType = "maglev"
+
+	// BoundedLoadAlgorithm is the consistent hashing, which moves keys of
+	// the overloaded shards to the next ones, the client reports the load
+	// via LoadTracker.
+	BoundedLoadAlgorithm AlgorithmType = "bounded-load"
 )
 
 func NewAlgo(
@@ -26,6 +33,13 @@ func NewAlgo(
 		return NewRendezvous(shards, hashFn), nil
 	case ConsistentAlgorithm:
 		return NewConsistent(shards, hashFn), nil
+	case JumpAlgorithm:
+		// jump needs the 64-bit hash, so hashFn isn't used.
+		return NewJump(shards, fnv64a), nil
+	case MaglevAlgorithm:
+		return NewMaglev(shards, hashFn), nil
+	case BoundedLoadAlgorithm:
+		return NewBoundedLoad(shards, hashFn), nil
 	default:
 		return nil, fmt.Errorf("unknown sharding algorithm: %s", algo)
 	}
@@ -34,14 +48,52 @@ func NewAlgo(
 var (
 	ErrShardAlreadyRegistered = errors.New("shard already registered")
 	ErrShardNotFound          = errors.New("shard not found")
+	ErrInvalidWeight          = fmt.Errorf("shard weight must be in [0, %d]", MaxWeight)
 )
 
+// MaxWeight is the largest weight of the shard, algorithms keep the state
+// proportional to the weights, for example consistent places virtualNodes
+// points per unit, so the weight is the relative size of the node, like
+// 1, 2 or 4, not its capacity.
+const MaxWeight = 100
+
 type hashFn func(key string) uint32
 
 type Shard struct {
 	ID   string `yaml:"id"`
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+
+	// Weight is the relative share of keys owned by the shard, for example
+	// shard with the weight 2 owns twice more keys, than the shard with the
+	// weight 1, zero weight is treated as 1, weights above MaxWeight are
+	// rejected.
+	Weight int `yaml:"weight"`
+}
+
+// Validate checks, that the weight of the shard is in [0, MaxWeight].
+func (s *Shard) Validate() error {
+	if s.Weight < 0 || s.Weight > MaxWeight {
+		return fmt.Errorf("%w: shard %s has %d", ErrInvalidWeight, s.ID, s.Weight)
+	}
+
+	return nil
+}
+
+func (s *Shard) weight() int {
+	return max(s.Weight, 1)
+}
+
+// mix32 is the murmur3 finalizer, it spreads the hash bits, because crc32
+// or fnv of similar keys, like "node1#1" and "node1#2", give correlated
+// hashes.
+func mix32(h uint32) uint32 {
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
 }
 
 type Algorithm interface {
@@ -67,6 +119,9 @@ func logDeleteErr(err error) {
 	}
 }
 
+// SyncShards makes the shards of the algorithm equal to the desired ones,
+// shards with the same ID, but another address or weight, are registered
+// again, so their weights are applied.
 func SyncShards(algo Algorithm, desired []*Shard) {
 	var (
 		desiredMap = make(map[string]*Shard, len(desired))
@@ -78,7 +133,7 @@ func SyncShards(algo Algorithm, desired []*Shard) {
 	}
 
 	for _, shard := range existing {
-		if _, ok := desiredMap[shard.ID]; !ok {
+		if d, ok := desiredMap[shard.ID]; !ok || *d != *shard {
 			logDeleteErr(algo.DeleteShard(shard))
 		}
 	}
@@ -3,6 +3,7 @@ package sharding
 import (
 	"fmt"
 	"github.com/stretchr/testify/require"
+	"math"
 	"testing"
 )
 
@@ -24,6 +25,18 @@ func TestNewAlgo(t *testing.T) {
 			algo:         ConsistentAlgorithm,
 			expectedType: &consistent{},
 		},
+		{
+			algo:         JumpAlgorithm,
+			expectedType: &jump{},
+		},
+		{
+			algo:         MaglevAlgorithm,
+			expectedType: &maglev{},
+		},
+		{
+			algo:         BoundedLoadAlgorithm,
+			expectedType: &boundedLoad{},
+		},
 		{
 			algo:          AlgorithmType("unknown"),
 			expectedType:  nil,
@@ -44,3 +57,131 @@ func TestNewAlgo(t *testing.T) {
 		})
 	}
 }
+
+func TestWeightedDistribution(t *testing.T) {
+	const keys = 100_000
+
+	var (
+		weights = []int{1, 2, 3, 4}
+		total   = 10
+	)
+
+	// tolerance is the allowed relative deviation of the shard share from
+	// its weight, keys are distributed almost independently by all, except
+	// consistent, ring share depends on the arcs, see TestConsistent_Balance.
+	testcases := []struct {
+		algo      AlgorithmType
+		tolerance func(weight int) float64
+	}{
+		{
+			algo:      NaiveAlgorithm,
+			tolerance: func(int) float64 { return 0.05 },
+		},
+		{
+			algo:      RendezvousAlgorithm,
+			tolerance: func(int) float64 { return 0.05 },
+		},
+		{
+			algo: ConsistentAlgorithm,
+			tolerance: func(weight int) float64 {
+				return 4 / math.Sqrt(float64(defaultVirtualNodes*weight))
+			},
+		},
+		{
+			algo:      JumpAlgorithm,
+			tolerance: func(int) float64 { return 0.05 },
+		},
+		{
+			algo:      MaglevAlgorithm,
+			tolerance: func(int) float64 { return 0.05 },
+		},
+	}
+
+	for _, tc := range testcases {
+		for name, hash := range map[string]hashFn{"crc32": crc32Hash, "fnv": fnvHash} {
+			t.Run(fmt.Sprintf("%s/%s", tc.algo, name), func(t *testing.T) {
+				shards := newShards(len(weights))
+				for i, w := range weights {
+					shards[i].Weight = w
+				}
+
+				algo, err := NewAlgo(tc.algo, shards, hash)
+				require.NoError(t, err)
+
+				owned := distribution(algo, keys)
+				for i, w := range weights {
+					expected := float64(keys*w) / float64(total)
+					got := float64(owned[shards[i].ID])
+					require.InDelta(t, 1, got/expected, tc.tolerance(w), "shard %s", shards[i].ID)
+				}
+			})
+		}
+	}
+}
+
+func TestZeroWeight(t *testing.T) {
+	for _, algo := range []AlgorithmType{
+		NaiveAlgorithm, RendezvousAlgorithm, ConsistentAlgorithm, JumpAlgorithm, MaglevAlgorithm,
+	} {
+		t.Run(string(algo), func(t *testing.T) {
+			shards := newShards(2)
+			shards[0].Weight = 0
+			shards[1].Weight = 1
+
+			a, err := NewAlgo(algo, shards, crc32Hash)
+			require.NoError(t, err)
+
+			owned := distribution(a, 10_000)
+			require.InDelta(t, owned["1"], owned["2"], 2_000)
+		})
+	}
+}
+
+func TestSyncShards(t *testing.T) {
+	for _, algo := range []AlgorithmType{
+		NaiveAlgorithm, RendezvousAlgorithm, ConsistentAlgorithm, JumpAlgorithm, MaglevAlgorithm, BoundedLoadAlgorithm,
+	} {
+		t.Run(string(algo), func(t *testing.T) {
+			a, err := NewAlgo(algo, newShards(3), crc32Hash)
+			require.NoError(t, err)
+
+			desired := newShards(4)[1:]
+			desired[0].Weight = 3
+			desired[1].Port = 50052
+			SyncShards(a, desired)
+
+			shards := a.GetShards()
+			require.Len(t, shards, 3)
+			for _, shard := range shards {
+				for _, d := range desired {
+					if d.ID == shard.ID {
+						require.Equal(t, *d, *shard)
+					}
+				}
+			}
+
+			// the weight of the shard is applied after the sync.
+			owned := distribution(a, 10_000)
+			require.Greater(t, owned["2"], owned["3"]+owned["4"])
+		})
+	}
+}
+
+func TestInvalidWeight(t *testing.T) {
+	for _, algo := range []AlgorithmType{
+		NaiveAlgorithm, RendezvousAlgorithm, ConsistentAlgorithm, JumpAlgorithm, MaglevAlgorithm, BoundedLoadAlgorithm,
+	} {
+		t.Run(string(algo), func(t *testing.T) {
+			a, err := NewAlgo(algo, newShards(1), crc32Hash)
+			require.NoError(t, err)
+
+			for _, weight := range []int{-1, MaxWeight + 1, 1_000_000} {
+				err = a.RegisterShard(&Shard{ID: "2", Weight: weight})
+				require.ErrorIs(t, err, ErrInvalidWeight)
+			}
+
+			require.NoError(t, a.RegisterShard(&Shard{ID: "2", Weight: MaxWeight}))
+			require.Len(t, a.GetShards(), 2)
+		})
+	}
+}
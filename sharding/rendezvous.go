@@ -1,6 +1,7 @@
 package sharding
 
 import (
+	"math"
 	"slices"
 	"sync"
 )
@@ -44,16 +45,20 @@ func (r *rendezvous) GetShard(key string) *Shard {
 	return r.shards[r.keys[idx]]
 }
 
+// getMaxShardIdx returns the shard with the highest score -w/ln(h), where
+// h is the hash of the key and the shard, mapped to (0, 1), so the shard
+// wins with the probability proportional to its weight w.
 func (r *rendezvous) getMaxShardIdx(key string) int {
 	var (
-		maxIdx  = -1
-		maxHash uint32
+		maxIdx   = -1
+		maxScore float64
 	)
 
 	for i, shardID := range r.keys {
-		hash := r.hash(key + shardID)
-		if hash >= maxHash {
-			maxHash, maxIdx = hash, i
+		h := (float64(mix32(r.hash(key+shardID))) + 0.5) / (1 << 32)
+		score := -float64(r.shards[shardID].weight()) / math.Log(h)
+		if score >= maxScore {
+			maxScore, maxIdx = score, i
 		}
 	}
 
@@ -68,6 +73,10 @@ func (r *rendezvous) RegisterShard(shard *Shard) error {
 }
 
 func (r *rendezvous) registerShardUnsafe(shard *Shard) error {
+	if err := shard.Validate(); err != nil {
+		return err
+	}
+
 	if r.exists(shard) {
 		return ErrShardAlreadyRegistered
 	}
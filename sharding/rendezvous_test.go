@@ -93,8 +93,9 @@ func TestRendezvous_Flow(t *testing.T) {
 				return 0
 			},
 			ops: func(s *rendezvous, hash hashFn) {
+				// hashes are mixed before scoring, mixed 1 is the highest one.
 				prev := s.GetShard("key")
-				require.Equal(t, prev, s.shards[s.keys[1]])
+				require.Equal(t, prev, s.shards[s.keys[0]])
 
 				require.NoError(t, s.RegisterShard(&Shard{Host: "localhost", Port: 8083, ID: "4"}))
 				curr := s.GetShard("key")
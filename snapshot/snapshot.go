@@ -0,0 +1,282 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/fadyat/speedy/eviction"
+	"go.uber.org/zap"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormatVersion is the version of the snapshot file, it's bumped on every
+// incompatible change of the format.
+//
+// File layout, integers are big endian or varints:
+//   - magic "SPDS" and uint16 format version,
+//   - uvarint version counter of the cache, it's missing in the format 1,
+//   - uvarint number of records,
+//   - for every record: uvarint key length, key, uvarint value length,
+//     value, uvarint version, varint deadline in unix nanoseconds, zero
+//     means no deadline,
+//   - uint32 crc32 of everything above.
+const FormatVersion uint16 = 2
+
+var magic = [4]byte{'S', 'P', 'D', 'S'}
+
+var (
+	ErrBadMagic           = errors.New("not a snapshot file")
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+	ErrCorrupted          = errors.New("snapshot is corrupted")
+)
+
+// Write stores the version counter and the records to the file at the
+// path, the file is written to the temporary file in the same directory
+// first and renamed after fsync, so the crash never leaves the partially
+// written snapshot.
+func Write(path string, version uint64, records []eviction.Record) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, base+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	// it's a no-op after the successful rename.
+	defer os.Remove(tmp.Name())
+
+	if err = encode(tmp, version, records); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rename snapshot: %w", err)
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes the rename durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot dir: %w", err)
+	}
+	defer d.Close()
+
+	if err = d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync snapshot dir: %w", err)
+	}
+
+	return nil
+}
+
+func encode(w io.Writer, version uint64, records []eviction.Record) error {
+	var (
+		crc = crc32.NewIEEE()
+		bw  = bufio.NewWriter(io.MultiWriter(w, crc))
+		buf = make([]byte, binary.MaxVarintLen64)
+	)
+
+	writeUvarint := func(v uint64) {
+		_, _ = bw.Write(buf[:binary.PutUvarint(buf, v)])
+	}
+
+	_, _ = bw.Write(magic[:])
+	_ = binary.Write(bw, binary.BigEndian, FormatVersion)
+	writeUvarint(version)
+	writeUvarint(uint64(len(records)))
+
+	for _, r := range records {
+		writeUvarint(uint64(len(r.Key)))
+		_, _ = bw.WriteString(r.Key)
+		writeUvarint(uint64(len(r.Value)))
+		_, _ = bw.Write(r.Value)
+		writeUvarint(r.Version)
+
+		var deadline int64
+		if !r.ExpiresAt.IsZero() {
+			deadline = r.ExpiresAt.UnixNano()
+		}
+
+		_, _ = bw.Write(buf[:binary.PutVarint(buf, deadline)])
+	}
+
+	// bufio.Writer keeps the first error, so it's enough to check it once.
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.BigEndian, crc.Sum32())
+}
+
+// Read loads the records from the file at the path, in the order they
+// were written, and the version counter, it's zero for the format 1.
+func Read(path string) ([]eviction.Record, uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return decode(data)
+}
+
+func decode(data []byte) ([]eviction.Record, uint64, error) {
+	const headerSize, crcSize = len(magic) + 2, 4
+	if len(data) < headerSize+crcSize || !bytes.Equal(data[:len(magic)], magic[:]) {
+		return nil, 0, ErrBadMagic
+	}
+
+	format := binary.BigEndian.Uint16(data[len(magic):])
+	if format != 1 && format != FormatVersion {
+		return nil, 0, fmt.Errorf("%w: %d", ErrUnsupportedVersion, format)
+	}
+
+	body, sum := data[:len(data)-crcSize], binary.BigEndian.Uint32(data[len(data)-crcSize:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, 0, fmt.Errorf("%w: checksum mismatch", ErrCorrupted)
+	}
+
+	var (
+		r       = bytes.NewReader(body[headerSize:])
+		version uint64
+		err     error
+	)
+
+	if format != 1 {
+		if version, err = binary.ReadUvarint(r); err != nil {
+			return nil, 0, fmt.Errorf("%w: %w", ErrCorrupted, err)
+		}
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+
+	records := make([]eviction.Record, 0, min(count, uint64(len(body))))
+	for i := uint64(0); i < count; i++ {
+		var rec eviction.Record
+		if rec, err = decodeRecord(r); err != nil {
+			return nil, 0, fmt.Errorf("%w: record %d: %w", ErrCorrupted, i, err)
+		}
+
+		records = append(records, rec)
+	}
+
+	if r.Len() != 0 {
+		return nil, 0, fmt.Errorf("%w: trailing data", ErrCorrupted)
+	}
+
+	return records, version, nil
+}
+
+func decodeRecord(r *bytes.Reader) (eviction.Record, error) {
+	readBytes := func() ([]byte, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if n > uint64(r.Len()) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	}
+
+	key, err := readBytes()
+	if err != nil {
+		return eviction.Record{}, err
+	}
+
+	val, err := readBytes()
+	if err != nil {
+		return eviction.Record{}, err
+	}
+
+	version, err := binary.ReadUvarint(r)
+	if err != nil {
+		return eviction.Record{}, err
+	}
+
+	deadline, err := binary.ReadVarint(r)
+	if err != nil {
+		return eviction.Record{}, err
+	}
+
+	rec := eviction.Record{Key: string(key), Value: val, Version: version}
+	if deadline != 0 {
+		rec.ExpiresAt = time.Unix(0, deadline)
+	}
+
+	return rec, nil
+}
+
+// Save writes the snapshot of the cache to the file at the path and
+// returns the number of saved keys.
+//
+// the counter is read after the records, so it's not less than their
+// versions.
+func Save(path string, algo eviction.Algorithm) (int, error) {
+	records := algo.Snapshot()
+	return len(records), Write(path, algo.Version(), records)
+}
+
+// Load restores the cache from the file at the path and returns the
+// number of loaded records, missing file is treated as the empty snapshot.
+func Load(path string, algo eviction.Algorithm) (int, error) {
+	records, version, err := Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	algo.Restore(records)
+	algo.AdvanceVersion(version)
+	return len(records), nil
+}
+
+// Run periodically saves the snapshot of the cache, until the context
+// is done, errors are logged, so the next attempt may succeed.
+func Run(ctx context.Context, path string, algo eviction.Algorithm, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := Save(path, algo); err != nil {
+				zap.L().Error("failed to save snapshot", zap.String("path", path), zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/fadyat/speedy/eviction"
+	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	deadline := time.Now().Add(time.Hour).Truncate(0)
+
+	testCases := []struct {
+		name    string
+		operate func(t *testing.T, path string)
+	}{
+		{
+			name: "write and read",
+			operate: func(t *testing.T, path string) {
+				records := []eviction.Record{
+					{Key: "foo", Value: []byte("bar"), Version: 1},
+					{Key: "", Value: []byte{}, Version: 2},
+					{Key: "baz", Value: make([]byte, 1<<16), Version: 1 << 40, ExpiresAt: deadline},
+				}
+
+				require.NoError(t, Write(path, 1<<41, records))
+
+				got, version, err := Read(path)
+				require.NoError(t, err)
+				require.Equal(t, uint64(1<<41), version)
+				require.Len(t, got, len(records))
+				for i := range records {
+					require.Equal(t, records[i].Key, got[i].Key)
+					require.Equal(t, records[i].Value, got[i].Value)
+					require.Equal(t, records[i].Version, got[i].Version)
+					require.True(t, records[i].ExpiresAt.Equal(got[i].ExpiresAt))
+				}
+			},
+		},
+		{
+			name: "empty snapshot",
+			operate: func(t *testing.T, path string) {
+				require.NoError(t, Write(path, 0, nil))
+
+				got, _, err := Read(path)
+				require.NoError(t, err)
+				require.Empty(t, got)
+			},
+		},
+		{
+			name: "save and load keep the order",
+			operate: func(t *testing.T, path string) {
+				source := eviction.NewLRU(3)
+				source.Put("foo", []byte("bar"), time.Hour)
+				source.Put("bar", []byte("baz"), 0)
+				source.Put("baz", []byte("qux"), 0)
+				source.Get("foo")
+
+				saved, err := Save(path, source)
+				require.NoError(t, err)
+				require.Equal(t, 3, saved)
+
+				restored := eviction.NewLRU(3)
+				loaded, err := Load(path, restored)
+				require.NoError(t, err)
+				require.Equal(t, 3, loaded)
+
+				// bar is the least recently used key, so it's evicted first.
+				restored.Put("qux", []byte("quux"), 0)
+				_, _, ok := restored.Get("bar")
+				require.False(t, ok)
+
+				val, version, ok := restored.Get("foo")
+				require.True(t, ok)
+				require.Equal(t, []byte("bar"), val)
+				require.Equal(t, uint64(1), version)
+			},
+		},
+		{
+			name: "versions of deleted keys aren't reused",
+			operate: func(t *testing.T, path string) {
+				source := eviction.NewLRU(3)
+				source.Put("foo", []byte("bar"), 0)
+				source.Put("bar", []byte("baz"), 0)
+				require.True(t, source.Delete("bar"))
+
+				_, err := Save(path, source)
+				require.NoError(t, err)
+
+				restored := eviction.NewLRU(3)
+				_, err = Load(path, restored)
+				require.NoError(t, err)
+				require.Equal(t, uint64(2), restored.Version())
+
+				// the client, which read bar before the restart, can't swap
+				// the new value with the same version.
+				require.Equal(t, uint64(3), restored.Put("bar", []byte("qux"), 0))
+			},
+		},
+		{
+			name: "format 1 has no version counter",
+			operate: func(t *testing.T, path string) {
+				require.NoError(t, Write(path, 7, []eviction.Record{{Key: "foo", Value: []byte("bar"), Version: 5}}))
+
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+
+				// the counter is the single byte right after the header.
+				data = append(data[:6:6], data[7:len(data)-4]...)
+				data[5] = 1
+				data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+				require.NoError(t, os.WriteFile(path, data, 0o600))
+
+				got, version, err := Read(path)
+				require.NoError(t, err)
+				require.Zero(t, version)
+				require.Equal(t, "foo", got[0].Key)
+				require.Equal(t, uint64(5), got[0].Version)
+			},
+		},
+		{
+			name: "load missing file",
+			operate: func(t *testing.T, path string) {
+				loaded, err := Load(path, eviction.NewLRU(3))
+				require.NoError(t, err)
+				require.Equal(t, 0, loaded)
+			},
+		},
+		{
+			name: "overwrite leaves no temp files",
+			operate: func(t *testing.T, path string) {
+				for i := 0; i < 3; i++ {
+					require.NoError(t, Write(path, 0, []eviction.Record{{Key: strconv.Itoa(i)}}))
+				}
+
+				entries, err := os.ReadDir(filepath.Dir(path))
+				require.NoError(t, err)
+				require.Len(t, entries, 1)
+
+				got, _, err := Read(path)
+				require.NoError(t, err)
+				require.Equal(t, "2", got[0].Key)
+			},
+		},
+		{
+			name: "failed write keeps the previous snapshot",
+			operate: func(t *testing.T, path string) {
+				if os.Geteuid() == 0 {
+					t.Skip("root ignores the permissions")
+				}
+
+				require.NoError(t, Write(path, 0, []eviction.Record{{Key: "foo"}}))
+				require.NoError(t, os.Chmod(filepath.Dir(path), 0o500))
+				t.Cleanup(func() { _ = os.Chmod(filepath.Dir(path), 0o700) })
+
+				require.Error(t, Write(path, 0, []eviction.Record{{Key: "bar"}}))
+
+				got, _, err := Read(path)
+				require.NoError(t, err)
+				require.Equal(t, "foo", got[0].Key)
+			},
+		},
+		{
+			name: "corrupted snapshot",
+			operate: func(t *testing.T, path string) {
+				require.NoError(t, Write(path, 0, []eviction.Record{{Key: "foo", Value: []byte("bar")}}))
+
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+
+				data[len(data)-6] ^= 0xff
+				require.NoError(t, os.WriteFile(path, data, 0o600))
+
+				_, _, err = Read(path)
+				require.ErrorIs(t, err, ErrCorrupted)
+
+				_, err = Load(path, eviction.NewLRU(3))
+				require.ErrorIs(t, err, ErrCorrupted)
+			},
+		},
+		{
+			name: "unsupported version",
+			operate: func(t *testing.T, path string) {
+				require.NoError(t, Write(path, 0, nil))
+
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+
+				data[5] = 0xff
+				require.NoError(t, os.WriteFile(path, data, 0o600))
+
+				_, _, err = Read(path)
+				require.ErrorIs(t, err, ErrUnsupportedVersion)
+			},
+		},
+		{
+			name: "not a snapshot",
+			operate: func(t *testing.T, path string) {
+				require.NoError(t, os.WriteFile(path, []byte("hello, world"), 0o600))
+
+				_, _, err := Read(path)
+				require.ErrorIs(t, err, ErrBadMagic)
+			},
+		},
+		{
+			name: "periodic snapshots",
+			operate: func(t *testing.T, path string) {
+				algo := eviction.NewLRU(3)
+				algo.Put("foo", []byte("bar"), 0)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					Run(ctx, path, algo, 10*time.Millisecond)
+				}()
+
+				// the temp dir is removed only after the last save.
+				defer func() {
+					cancel()
+					<-done
+				}()
+
+				require.Eventually(t, func() bool {
+					got, _, err := Read(path)
+					return err == nil && len(got) == 1
+				}, time.Second, 10*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.operate(t, filepath.Join(t.TempDir(), "cache.snapshot"))
+		})
+	}
+}